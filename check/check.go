@@ -0,0 +1,286 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"bufio"
+	"cmp"
+	"flag"
+	"fmt"
+	"golang.org/x/text/message"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Checks that a family of sieve files is consistent. Each sieve must have the
+shape expected for its order, every entry must have all even digits and follow
+a power of two that doesn't carry, the entries of each sieve must be exactly
+the projections of the next larger one, and the number of entries must match
+the tables in the documentation.
+*/
+
+// sieveFile is a sieve along with where it came from
+type sieveFile struct {
+	name   string
+	config common.LoopAccelerator
+}
+
+func main() {
+	docs := flag.String("docs", "README.md,cycle/README.md", "Comma separated list of files with tables of sieve sizes to check against")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [directory]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sieves, err := loadSieves(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(sieves) == 0 {
+		log.Fatalf("no sieve files in %s", dir)
+	}
+
+	p := message.NewPrinter(message.MatchLanguage("en"))
+	var problems []string
+	for i, s := range sieves {
+		_, _ = p.Printf("%-24s order %2d, %d entries\n", s.name, s.config.Order, len(s.config.Index))
+		problems = append(problems, checkSieve(p, s)...)
+		if i > 0 {
+			problems = append(problems, checkLift(p, sieves[i-1], s)...)
+		}
+	}
+	if *docs != "" {
+		for _, doc := range strings.Split(*docs, ",") {
+			table, err := readTable(doc)
+			if err != nil {
+				log.Fatal(err)
+			}
+			problems = append(problems, checkTable(p, sieves, doc, table)...)
+		}
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		_, _ = p.Printf("%d problems\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("all sieves are consistent")
+}
+
+// loadSieves reads every cycle-*.json file in a directory in order of the
+// number of digits
+func loadSieves(dir string) ([]sieveFile, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "cycle-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sieves := make([]sieveFile, 0, len(names))
+	for _, name := range names {
+		config, err := common.ReadAccelerator(name)
+		if err != nil {
+			return nil, err
+		}
+		sieves = append(sieves, sieveFile{name: filepath.Base(name), config: config})
+	}
+	slices.SortFunc(sieves, func(a, b sieveFile) int {
+		return cmp.Compare(a.config.Order, b.config.Order)
+	})
+	for i := 1; i < len(sieves); i++ {
+		if sieves[i].config.Order == sieves[i-1].config.Order {
+			return nil, fmt.Errorf("%s and %s both have order %d", sieves[i-1].name, sieves[i].name, sieves[i].config.Order)
+		}
+	}
+	return sieves, nil
+}
+
+// cycleLength returns 10^order and 4*5^(order-1), the modulus and the length
+// of the cycle of powers of two for a sieve of the given order
+func cycleLength(order int) (uint64, uint64) {
+	mask := uint64(10)
+	length := uint64(4)
+	for i := 1; i < order; i++ {
+		mask *= 10
+		length *= 5
+	}
+	return mask, length
+}
+
+// checkSieve checks one sieve on its own. Each entry must have all even digits
+// and the power before it must not carry when doubled. A sieve built without
+// the carry rule has about twice as many entries and fails the second test for
+// about half of them.
+func checkSieve(p *message.Printer, s sieveFile) []string {
+	var problems []string
+	report := func(format string, args ...any) {
+		problems = append(problems, s.name+": "+p.Sprintf(format, args...))
+	}
+
+	config := s.config
+	if config.Order < 1 || config.Order > 18 {
+		report("order %d is out of range", config.Order)
+		return problems
+	}
+	mask, length := cycleLength(config.Order)
+	if config.Mask != mask {
+		report("mask is %d, not %d", config.Mask, mask)
+	}
+	if config.Length != length {
+		report("cycle length is %d, not %d", config.Length, length)
+	}
+	if config.Leadin != uint64(config.Order) {
+		report("leadin is %d, not %d", config.Leadin, config.Order)
+	}
+	if config.EvenItems != len(config.Index) {
+		report("EvenItems is %d but there are %d entries", config.EvenItems, len(config.Index))
+	}
+	if len(problems) > 0 {
+		// the entries can't be checked against the wrong modulus
+		return problems
+	}
+
+	odd, carry, residues := 0, 0, 0
+	prev := uint64(0)
+	for i, k := range config.Index {
+		if k <= prev || k <= config.Leadin || k > config.Leadin+config.Length {
+			report("index %d is out of order or outside the cycle", k)
+			return problems
+		}
+		prev = k
+		r := common.PowMod64(2, k, mask)
+		if !evenDigits(r, config.Order) {
+			odd++
+		}
+		if common.PowMod64(2, k-1, mask) >= mask/2 {
+			carry++
+		}
+		if config.Residue != nil && config.Residue[i] != r {
+			residues++
+		}
+	}
+	if odd > 0 {
+		report("%d of %d entries have an odd digit", odd, len(config.Index))
+	}
+	if carry > 0 {
+		report("%d of %d entries follow a power that carries, the no-carry rule wasn't applied", carry, len(config.Index))
+	}
+	if residues > 0 {
+		report("%d of %d residues are wrong", residues, len(config.Residue))
+	}
+	return problems
+}
+
+// checkLift checks that the entries of the smaller sieve are exactly the
+// indexes of the larger one reduced modulo the smaller cycle length. Every
+// entry of the larger sieve must reduce to an entry of the smaller one since
+// its low digits are all even without a carry, and every entry of the smaller
+// sieve has at least one lift.
+func checkLift(p *message.Printer, small, big sieveFile) []string {
+	var problems []string
+	length := small.config.Length
+	if length == 0 || big.config.Length%length != 0 {
+		return []string{p.Sprintf("%s: cycle length %d isn't a multiple of %d from %s", big.name, big.config.Length, length, small.name)}
+	}
+	entries := map[uint64]bool{}
+	for _, k := range small.config.Index {
+		entries[k] = true
+	}
+	lifts := map[uint64]int{}
+	stray := 0
+	for _, k := range big.config.Index {
+		k = k % length
+		if k <= small.config.Leadin {
+			k += length
+		}
+		if entries[k] {
+			lifts[k]++
+		} else {
+			stray++
+		}
+	}
+	if stray > 0 {
+		problems = append(problems, p.Sprintf("%s: %d entries reduce to indexes that aren't in %s", big.name, stray, small.name))
+	}
+	if len(lifts) < len(entries) {
+		problems = append(problems, p.Sprintf("%s: %d entries have no lift to %s", small.name, len(entries)-len(lifts), big.name))
+	}
+	return problems
+}
+
+// readTable finds the number of sieve entries for each order in a documented
+// table. Tables are the output of the cycle generator, either as printed or as
+// a markdown table, where each row starts with the number of digits, the
+// leadin, and the cycle length, followed by the two flags, the last value in
+// the leadin and the number of entries.
+func readTable(name string) (map[int]int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table := map[int]int{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(strings.ReplaceAll(scanner.Text(), "|", " "))
+		if len(fields) != 8 {
+			continue
+		}
+		order, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		if _, err := strconv.ParseBool(fields[3]); err != nil {
+			continue
+		}
+		even, err := strconv.Atoi(strings.ReplaceAll(fields[6], ",", ""))
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad count %q for order %d", name, fields[6], order)
+		}
+		if previous, ok := table[order]; ok && previous != even {
+			return nil, fmt.Errorf("%s: order %d is listed with both %d and %d entries", name, order, previous, even)
+		}
+		table[order] = even
+	}
+	return table, scanner.Err()
+}
+
+// checkTable compares the number of entries in each sieve with a documented
+// table
+func checkTable(p *message.Printer, sieves []sieveFile, doc string, table map[int]int) []string {
+	var problems []string
+	for _, s := range sieves {
+		even, ok := table[s.config.Order]
+		if ok && even != len(s.config.Index) {
+			problems = append(problems, p.Sprintf("%s: %d entries but %s says %d", s.name, len(s.config.Index), doc, even))
+		}
+	}
+	return problems
+}
+
+// evenDigits checks that all digits of x are even, including the leading
+// zeros that make it `digits` long
+func evenDigits(x uint64, digits int) bool {
+	for i := 0; i < digits; i++ {
+		if x%10%2 == 1 {
+			return false
+		}
+		x /= 10
+	}
+	return true
+}
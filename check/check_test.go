@@ -0,0 +1,86 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/message"
+	"slices"
+	"testing"
+)
+
+func TestCheckSieve(t *testing.T) {
+	p := message.NewPrinter(message.MatchLanguage("en"))
+	config, err := common.ReadAccelerator("../cycle-003.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	s := sieveFile{name: "cycle-003.json", config: config}
+	assert.Empty(t, checkSieve(p, s))
+
+	// 2^22 = 4194304 ends in 304 which has an odd digit, 2^30 ends in 824
+	// which is all even but follows 2^29 = ...912 which carries
+	bad := s
+	bad.config.Index = append([]uint64{22, 30}, config.Index...)
+	slices.Sort(bad.config.Index)
+	bad.config.Residue = nil
+	for _, k := range bad.config.Index {
+		bad.config.Residue = append(bad.config.Residue, common.PowMod64(2, k, config.Mask))
+	}
+	bad.config.EvenItems = len(bad.config.Index)
+	assert.Equal(t, []string{
+		"cycle-003.json: 1 of 14 entries have an odd digit",
+		"cycle-003.json: 1 of 14 entries follow a power that carries, the no-carry rule wasn't applied",
+	}, checkSieve(p, bad))
+
+	// the shipped sieves have residues, so they are checked too
+	assert.NotNil(t, config.Residue)
+	bad = s
+	bad.config.Residue = slices.Clone(config.Residue)
+	bad.config.Residue[3] += 200
+	assert.Equal(t, []string{"cycle-003.json: 1 of 12 residues are wrong"}, checkSieve(p, bad))
+
+	bad = s
+	bad.config.Leadin = 2
+	assert.Equal(t, []string{"cycle-003.json: leadin is 2, not 3"}, checkSieve(p, bad))
+}
+
+func TestCheckLift(t *testing.T) {
+	p := message.NewPrinter(message.MatchLanguage("en"))
+	sieves, err := loadSieves("..")
+	if err != nil || len(sieves) < 2 {
+		t.Skip("no sieve files")
+	}
+	for i := 1; i < len(sieves); i++ {
+		assert.Empty(t, checkLift(p, sieves[i-1], sieves[i]), sieves[i].name)
+	}
+
+	// cycle-002 has indexes 3, 6, 10, 11 and 19 which reduce to 3, 2, 2, 3
+	// and 3 in the cycle of length 4
+	small, big := sieves[0], sieves[1]
+	small.config.Index = []uint64{3}
+	assert.Equal(t, []string{"cycle-002.json: 2 entries reduce to indexes that aren't in cycle-001.json"}, checkLift(p, small, big))
+	big.config.Index = []uint64{6, 10}
+	small.config.Index = []uint64{2, 3}
+	assert.Equal(t, []string{"cycle-001.json: 1 entries have no lift to cycle-002.json"}, checkLift(p, small, big))
+}
+
+func TestReadTable(t *testing.T) {
+	table, err := readTable("../README.md")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, table[2])
+	assert.Equal(t, 112846, table[13])
+	assert.Equal(t, 282111, table[14])
+	assert.Len(t, table, 14)
+
+	// the documented tables agree with the sieves in the repository
+	sieves, err := loadSieves("..")
+	if err != nil || len(sieves) == 0 {
+		t.Skip("no sieve files")
+	}
+	p := message.NewPrinter(message.MatchLanguage("en"))
+	for _, doc := range []string{"../README.md", "../cycle/README.md"} {
+		table, err := readTable(doc)
+		assert.NoError(t, err)
+		assert.Empty(t, checkTable(p, sieves, doc, table))
+	}
+}
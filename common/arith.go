@@ -0,0 +1,22 @@
+package common
+
+import "math/bits"
+
+// MulMod64 returns a * b mod m without overflow. Both a and b must be less than m.
+func MulMod64(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+// PowMod64 returns a^n mod m
+func PowMod64(a, n, m uint64) uint64 {
+	r := uint64(1) % m
+	a = a % m
+	for ; n > 0; n >>= 1 {
+		if n&1 != 0 {
+			r = MulMod64(r, a, m)
+		}
+		a = MulMod64(a, a, m)
+	}
+	return r
+}
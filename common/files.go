@@ -0,0 +1,65 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteFileAtomic writes data to a file so that readers see either the old
+// contents or the new, never a mix. The data goes to a temporary file in the
+// same directory, which is then renamed over the target. Concurrent writers
+// of the same file can't corrupt it, though the last one to finish wins. As
+// with os.WriteFile, the file is created with perm less the process umask.
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	f, err := createTemp(name, perm)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+// createTemp makes a new hidden file next to `name`. Unlike os.CreateTemp, the
+// file gets the given permissions, which the system filters through the umask.
+func createTemp(name string, perm os.FileMode) (*os.File, error) {
+	for {
+		tmp := filepath.Join(filepath.Dir(name), fmt.Sprintf(".%s.%d.tmp", filepath.Base(name), rand.Uint32()))
+		f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// NewRunID returns a name for a run that sorts by start time and is very
+// unlikely to be shared with any other run, even one started at the same moment
+// on the same machine
+func NewRunID() string {
+	return fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405Z"), os.Getpid())
+}
+
+// OutputDir makes sure that a directory for results exists
+func OutputDir(dir string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("can't create output directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "records.json")
+
+	assert.NoError(t, WriteFileAtomic(name, []byte("a much longer first version"), 0640))
+	assert.NoError(t, WriteFileAtomic(name, []byte("short"), 0640))
+	txt, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "short", string(txt), "nothing should be left over from the longer file")
+
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// no temporary files are left behind, even after a failure
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "x.json"), nil, 0666))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestNewRunID(t *testing.T) {
+	id := NewRunID()
+	assert.Regexp(t, `^\d{8}T\d{6}Z-\d+$`, id)
+	assert.NoError(t, OutputDir(filepath.Join(t.TempDir(), "a", "b")))
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWriteFileAtomicUmask(t *testing.T) {
+	// the umask belongs to the whole process, so this test can't run in
+	// parallel with anything that creates files
+	old := syscall.Umask(027)
+	defer syscall.Umask(old)
+
+	dir := t.TempDir()
+	for _, c := range []struct {
+		perm, want os.FileMode
+	}{
+		{0666, 0640},
+		{0644, 0640},
+		{0600, 0600},
+		{0400, 0400},
+	} {
+		name := filepath.Join(dir, "sieve.json")
+		assert.NoError(t, WriteFileAtomic(name, []byte("{}"), c.perm))
+		info, err := os.Stat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, info.Mode().Perm(), "perm %o", c.perm)
+	}
+}
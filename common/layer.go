@@ -0,0 +1,169 @@
+package common
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// SieveLayer refines a base sieve with information from more digits. Powers of
+// two modulo a larger power of ten repeat every Period batches of the base
+// sieve, so whether entry i of the base sieve survives in batch j depends only
+// on i and j mod Period. Each of the Period rows of Bits is a bitmap over the
+// entries of the base sieve with a bit set for every survivor.
+type SieveLayer struct {
+	Name   string
+	Order  int
+	Period uint64
+	Width  int
+	Bits   []uint64
+}
+
+func newSieveLayer(name string, order int, period uint64, width int) SieveLayer {
+	return SieveLayer{
+		Name:   name,
+		Order:  order,
+		Period: period,
+		Width:  width,
+		Bits:   make([]uint64, period*uint64(RowWords(width))),
+	}
+}
+
+// RowWords is the number of words needed to hold one bit for each of `width` entries
+func RowWords(width int) int {
+	return (width + 63) / 64
+}
+
+// Row returns the bitmap of base sieve entries that survive this layer in `batch`
+func (l *SieveLayer) Row(batch uint64) []uint64 {
+	w := uint64(RowWords(l.Width))
+	r := batch % l.Period
+	return l.Bits[r*w : (r+1)*w]
+}
+
+func (l *SieveLayer) set(r uint64, i int) {
+	l.Bits[r*uint64(RowWords(l.Width))+uint64(i/64)] |= 1 << (i % 64)
+}
+
+// Survivors counts the entries that survive this layer, over all Period rows
+func (l *SieveLayer) Survivors() int {
+	n := 0
+	for _, w := range l.Bits {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// AddLayer stacks another layer on top of the sieve
+func (config *LoopAccelerator) AddLayer(layer SieveLayer) error {
+	if layer.Width != len(config.Index) {
+		return fmt.Errorf("layer %s has %d entries but the sieve has %d", layer.Name, layer.Width, len(config.Index))
+	}
+	config.Layers = append(config.Layers, layer)
+	return nil
+}
+
+// Survivors fills `dst` with the bitmap of sieve entries that pass every layer
+// in `batch` and returns it. If there are no layers, nil is returned and every
+// entry should be considered a survivor.
+//
+// A layer of order m describes 2^n only once n > m, where powers of two have
+// entered their cycle modulo 10^m, so no layers are applied until every
+// candidate in the batch is that far along. See firstLayerBatch.
+func (config *LoopAccelerator) Survivors(batch uint64, dst []uint64) []uint64 {
+	if len(config.Layers) == 0 || batch < config.firstLayerBatch() {
+		return nil
+	}
+	dst = dst[:RowWords(len(config.Index))]
+	copy(dst, config.Layers[0].Row(batch))
+	for _, layer := range config.Layers[1:] {
+		for k, w := range layer.Row(batch) {
+			dst[k] &= w
+		}
+	}
+	return dst
+}
+
+// firstLayerBatch returns the first batch whose candidates 2^n all have n
+// greater than the order of every layer. Indexes are in increasing order, so
+// the first entry has the smallest n in each batch.
+func (config *LoopAccelerator) firstLayerBatch() uint64 {
+	order := uint64(0)
+	for _, layer := range config.Layers {
+		order = max(order, uint64(layer.Order))
+	}
+	if len(config.Index) == 0 || config.Index[0] > order {
+		return 0
+	}
+	return (order - config.Index[0] + config.Length) / config.Length
+}
+
+// LiftLayer builds a layer that tests the digit at position Order+1, counting
+// from 0 at the units digit.
+//
+// A candidate in batch j of the sieve is 2^n with n = j*Length + Index[i]. The
+// sieve fixes the low Order digits, positions 0 through Order-1, and only
+// admits candidates where doubling 2^(n-1) does not carry out of them, so the
+// digit at position Order is already known to be even. The digit at position
+// Order+1 is odd exactly when doubling 2^(n-1) carries out of the low Order+1
+// digits. Modulo 10^(Order+1), powers of two repeat with period 5*Length so
+// that carry depends only on i and j mod 5. That period only starts at
+// 2^(Order+1), and the first entry of the sieve can have Index Order+1, so each
+// row is seeded from an exponent five batches on, where every entry is inside
+// the period.
+//
+// All of this only needs 64-bit arithmetic so it is limited to sieves with
+// fewer than 19 digits.
+func LiftLayer(config LoopAccelerator) (SieveLayer, error) {
+	if config.Order+1 > 19 {
+		return SieveLayer{}, fmt.Errorf("sieve of order %d is too large for the lift filter", config.Order)
+	}
+	modulus := uint64(10)
+	for i := 0; i < config.Order; i++ {
+		modulus *= 10
+	}
+
+	layer := newSieveLayer("lift", config.Order+1, 5, len(config.Index))
+	stride := PowMod64(2, config.Length, modulus)
+	for i, k := range config.Index {
+		// z is the value that gets doubled to reach the candidate
+		z := PowMod64(2, k-1+5*config.Length, modulus)
+		for r := uint64(0); r < 5; r++ {
+			if 2*z < modulus {
+				layer.set(r, i)
+			}
+			z = MulMod64(z, stride, modulus)
+		}
+	}
+	return layer, nil
+}
+
+// HigherLayer builds a layer from a sieve with more digits than the base
+// sieve. The cycle of the higher sieve is 5^m times as long as the cycle of
+// the base sieve, so it covers 5^m consecutive batches. Every survivor of the
+// higher sieve is also a survivor of the base sieve, so each one marks a
+// single entry of the base sieve in one of those batches. Only the bitmap is
+// kept so the memory cost is one bit per base entry per batch instead of the
+// full higher sieve. The entries of the higher sieve start at Order+1, so the
+// layer says nothing about candidates before that, which Survivors allows for.
+func HigherLayer(name string, base, higher LoopAccelerator) (SieveLayer, error) {
+	if higher.Order <= base.Order || higher.Length%base.Length != 0 {
+		return SieveLayer{}, fmt.Errorf("%s (order %d) does not refine a sieve of order %d", name, higher.Order, base.Order)
+	}
+	period := higher.Length / base.Length
+
+	position := make(map[uint64]int, len(base.Index))
+	for i, k := range base.Index {
+		position[k%base.Length] = i
+	}
+
+	layer := newSieveLayer(name, higher.Order, period, len(base.Index))
+	for _, x := range higher.Index {
+		i, ok := position[x%base.Length]
+		if !ok {
+			return SieveLayer{}, fmt.Errorf("%s has index %d which is not in the base sieve", name, x)
+		}
+		offset := (x + higher.Length - base.Index[i]%higher.Length) % higher.Length
+		layer.set(offset/base.Length, i)
+	}
+	return layer, nil
+}
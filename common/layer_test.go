@@ -0,0 +1,134 @@
+package common
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+// inSieve reports whether 2^n is a survivor of the sieve of the given order,
+// computed directly with big integers: the low digits are all even and
+// doubling 2^(n-1) doesn't carry out of them
+func inSieve(n uint64, order int) bool {
+	mask := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(order)), nil)
+	low := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(n), mask)
+	for _, d := range low.Text(10) {
+		if (d-'0')%2 == 1 {
+			return false
+		}
+	}
+	prev := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(n-1), mask)
+	return prev.Lsh(prev, 1).Cmp(mask) < 0
+}
+
+// checkLayer compares every bit of every row of a layer with a direct
+// calculation, over enough batches to use every row twice
+func checkLayer(t *testing.T, config LoopAccelerator, layer SieveLayer) {
+	for j := uint64(0); j < 2*layer.Period; j++ {
+		row := layer.Row(j)
+		for i, k := range config.Index {
+			n := j*config.Length + k
+			if n <= uint64(layer.Order) {
+				// the layer doesn't apply before the higher cycle starts
+				continue
+			}
+			set := row[i/64]&(1<<(i%64)) != 0
+			if !assert.Equal(t, inSieve(n, layer.Order), set, "%s on order %d, n = %d", layer.Name, config.Order, n) {
+				return
+			}
+		}
+	}
+}
+
+func TestLiftLayer(t *testing.T) {
+	for _, name := range []string{"../cycle-001.json", "../cycle-002.json", "../cycle-003.json", "../cycle-006.json"} {
+		config, err := ReadAccelerator(name)
+		if err != nil {
+			t.Skipf("no sieve file %s", name)
+		}
+		layer, err := LiftLayer(config)
+		assert.NoError(t, err)
+		assert.Equal(t, config.Order+1, layer.Order)
+		checkLayer(t, config, layer)
+	}
+
+	// each survivor is an entry of the next sieve, so they match in number
+	for order, want := range map[string]int{"../cycle-001.json": 5, "../cycle-002.json": 12} {
+		config, err := ReadAccelerator(order)
+		if err != nil {
+			t.Skip("no sieve file")
+		}
+		layer, err := LiftLayer(config)
+		assert.NoError(t, err)
+		assert.Equal(t, want, layer.Survivors(), order)
+	}
+}
+
+func TestHigherLayer(t *testing.T) {
+	sieves := map[int]LoopAccelerator{}
+	for _, order := range []int{1, 2, 3, 6} {
+		config, err := ReadAccelerator(fmt.Sprintf("../cycle-%03d.json", order))
+		if err != nil {
+			t.Skip("no sieve file")
+		}
+		sieves[order] = config
+	}
+	for _, pair := range [][2]int{{1, 2}, {2, 3}, {3, 6}, {1, 6}} {
+		base, higher := sieves[pair[0]], sieves[pair[1]]
+		layer, err := HigherLayer(fmt.Sprintf("cycle-%03d", pair[1]), base, higher)
+		assert.NoError(t, err)
+		assert.Equal(t, higher.Length/base.Length, layer.Period)
+		checkLayer(t, base, layer)
+	}
+
+	_, err := HigherLayer("cycle-002", sieves[3], sieves[2])
+	assert.Error(t, err)
+}
+
+func TestSurvivors(t *testing.T) {
+	base, err := ReadAccelerator("../cycle-001.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	higher, err := ReadAccelerator("../cycle-006.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	dst := make([]uint64, RowWords(len(base.Index)))
+	assert.Nil(t, base.Survivors(1, dst), "no layers")
+
+	layer, err := HigherLayer("cycle-006", base, higher)
+	assert.NoError(t, err)
+	assert.NoError(t, base.AddLayer(layer))
+	lift, err := LiftLayer(base)
+	assert.NoError(t, err)
+	assert.NoError(t, base.AddLayer(lift))
+
+	// 2^6 is in batch 1 and comes before the cycle modulo 10^6, so the
+	// layers can't be used until batch 2 where n is at least 10
+	assert.Equal(t, uint64(2), base.firstLayerBatch())
+	assert.Nil(t, base.Survivors(1, dst))
+	assert.NotNil(t, base.Survivors(2, dst))
+
+	// every solution with all even digits survives
+	for _, n := range []uint64{2, 3, 6, 11} {
+		j, i := (n-base.Index[0])/base.Length, -1
+		for k, x := range base.Index {
+			if j*base.Length+x == n {
+				i = k
+			}
+		}
+		live := base.Survivors(j, dst)
+		assert.True(t, live == nil || live[i/64]&(1<<(i%64)) != 0, "n = %d", n)
+	}
+
+	// survivors are the candidates that pass every layer
+	for j := uint64(2); j < 2*layer.Period; j++ {
+		live := base.Survivors(j, dst)
+		for i, k := range base.Index {
+			n := j*base.Length + k
+			assert.Equal(t, inSieve(n, 6), live[i/64]&(1<<(i%64)) != 0, "n = %d", n)
+		}
+	}
+}
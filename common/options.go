@@ -1,48 +1,126 @@
 package common
 
 import (
-	"log"
+	"fmt"
+	"math/bits"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
-func DecodeLimit(limitString *string, verbose *bool) uint64 {
-	decoder := regexp.MustCompile(`([0-9_]+)([MGTPE]*)`)
-	pieces := decoder.FindStringSubmatch(*limitString)
-	lx, err := strconv.ParseInt(strings.Replace(pieces[1], "_", "", -1), 10, 64)
-	limit := uint64(lx)
+// limitPattern matches a count such as 10G, 1_000_000, 1e15, 2^50 or 16Mi. The
+// pieces are the digits, an optional power of ten or exponent and an optional
+// suffix. Only one suffix is allowed so something like 5MG is rejected.
+var limitPattern = regexp.MustCompile(`^([0-9][0-9_]*)(?:e([0-9]+)|\^([0-9]+))?([kKMGTPE]i?)?$`)
+
+// ParseLimit converts a count such as "10G" into a number. The number can have
+// underscores between digits and can be followed by e and a power of ten as in
+// 1e15 or by ^ and an exponent as in 2^50. A single suffix may follow. K, M, G,
+// T, P and E are powers of 1000 while Ki, Mi, Gi, Ti, Pi and Ei are powers of
+// 1024. An error is returned if the text doesn't have this form or if the value
+// doesn't fit in 64 bits.
+func ParseLimit(s string) (uint64, error) {
+	pieces := limitPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if pieces == nil {
+		return 0, fmt.Errorf("invalid limit %q, expected a form like 10G, 1e15, 2^50 or 16Mi", s)
+	}
+	digits := strings.ReplaceAll(pieces[1], "_", "")
+	if strings.HasSuffix(pieces[1], "_") || strings.Contains(pieces[1], "__") {
+		return 0, fmt.Errorf("invalid limit %q, underscores must separate digits", s)
+	}
+	limit, err := strconv.ParseUint(digits, 10, 64)
 	if err != nil {
-		log.Fatal(err)
-	}
-	for _, s := range pieces[2] {
-		switch s {
-		case 'M':
-			limit *= 1_000_000
-		case 'G':
-			limit *= 1_000_000_000
-		case 'T':
-			limit *= 1_000_000_000_000
-		case 'P':
-			limit *= 1_000_000_000_000_000
-		case 'E':
-			limit *= 1_000_000_000_000_000_000
-		default:
-			log.Fatalf(`Unrecognized limit format '%c' from "%s", can't happen`, s, pieces[2])
+		return 0, fmt.Errorf("limit %q doesn't fit in 64 bits", s)
+	}
+
+	ok := true
+	switch {
+	case pieces[2] != "":
+		limit, ok = scaleLimit(limit, 10, pieces[2])
+	case pieces[3] != "":
+		limit, ok = powLimit(limit, pieces[3])
+	}
+	if ok && pieces[4] != "" {
+		base := uint64(1000)
+		if strings.HasSuffix(pieces[4], "i") {
+			base = 1024
 		}
+		power := strings.Index("KMGTPE", strings.ToUpper(pieces[4][:1])) + 1
+		limit, ok = scaleLimit(limit, base, strconv.Itoa(power))
+	}
+	if !ok {
+		return 0, fmt.Errorf("limit %q doesn't fit in 64 bits", s)
+	}
+	return limit, nil
+}
+
+// ParseRange converts either a single limit or a pair of limits separated by
+// ".." into the start and end of a range of values. A single limit is the end
+// of a range that starts at zero.
+func ParseRange(s string) (uint64, uint64, error) {
+	first, last, found := strings.Cut(s, "..")
+	if !found {
+		end, err := ParseLimit(s)
+		return 0, end, err
+	}
+	start, err := ParseLimit(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := ParseLimit(last)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("range %q ends before it starts", s)
 	}
-	if *verbose {
-		if limit >= 1_000_000_000_000_000 {
-			log.Printf(`Limit: %.1fP`, float64(limit)/1e15)
-		} else if limit >= 1_000_000_000_000 {
-			log.Printf(`Limit: %.1fT`, float64(limit)/1e12)
-		} else if limit >= 1_000_000_000 {
-			log.Printf(`Limit: %.1fG`, float64(limit)/1e9)
-		} else if limit >= 1_000_000 {
-			log.Printf(`Limit: %.1fM`, float64(limit)/1e6)
-		} else {
-			log.Printf("Limit: %d", limit)
+	return start, end, nil
+}
+
+// FormatLimit gives a short approximate form of a limit for log messages
+func FormatLimit(limit uint64) string {
+	switch {
+	case limit >= 1_000_000_000_000_000:
+		return fmt.Sprintf("%.1fP", float64(limit)/1e15)
+	case limit >= 1_000_000_000_000:
+		return fmt.Sprintf("%.1fT", float64(limit)/1e12)
+	case limit >= 1_000_000_000:
+		return fmt.Sprintf("%.1fG", float64(limit)/1e9)
+	case limit >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(limit)/1e6)
+	}
+	return strconv.FormatUint(limit, 10)
+}
+
+// scaleLimit multiplies x by base^power where power is a decimal string. The
+// flag is false on overflow.
+func scaleLimit(x uint64, base uint64, power string) (uint64, bool) {
+	scale, ok := powLimit(base, power)
+	if !ok {
+		return 0, false
+	}
+	hi, lo := bits.Mul64(x, scale)
+	return lo, hi == 0
+}
+
+// powLimit computes base^power where power is a decimal string. The flag is
+// false on overflow.
+func powLimit(base uint64, power string) (uint64, bool) {
+	n, err := strconv.ParseUint(power, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	r := uint64(1)
+	for ; n > 0; n-- {
+		if base <= 1 {
+			// no overflow is possible and looping is pointless
+			return base, true
+		}
+		hi, lo := bits.Mul64(r, base)
+		if hi != 0 {
+			return 0, false
 		}
+		r = lo
 	}
-	return limit
+	return r, true
 }
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestParseLimit(t *testing.T) {
+	good := map[string]uint64{
+		"0":                    0,
+		"17":                   17,
+		"1_000_000":            1_000_000,
+		"10G":                  10_000_000_000,
+		"3K":                   3_000,
+		"3k":                   3_000,
+		"1e15":                 1_000_000_000_000_000,
+		"25e2K":                2_500_000,
+		"2^50":                 1 << 50,
+		"10^3M":                1_000_000_000,
+		"16Mi":                 16 << 20,
+		"1Ki":                  1024,
+		"1Ei":                  1 << 60,
+		"15Ei":                 15 << 60,
+		"18E":                  18_000_000_000_000_000_000,
+		"2^63":                 1 << 63,
+		"1^1000000":            1,
+		"0^0":                  1,
+		" 5M ":                 5_000_000,
+		"1e19":                 10_000_000_000_000_000_000,
+		"18446744073709551615": math.MaxUint64,
+	}
+	for s, want := range good {
+		limit, err := ParseLimit(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, want, limit, s)
+		}
+	}
+
+	bad := []string{
+		"", "G", "5MG", "5GM", "10G junk", "1.5G", "-3", "1e", "2^", "_1", "1__0", "10_",
+		"19E", "16Ei", "2^64", "1e20", "18446744073709551616", "99999999999999999999999",
+		"3Ki5", "5X", "0x10",
+	}
+	for _, s := range bad {
+		_, err := ParseLimit(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	start, end, err := ParseRange("10P..20P")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10_000_000_000_000_000), start)
+	assert.Equal(t, uint64(20_000_000_000_000_000), end)
+
+	start, end, err = ParseRange("2^40")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), start)
+	assert.Equal(t, uint64(1<<40), end)
+
+	for _, s := range []string{"20P..10P", "..5G", "5G..", "1G..2G..3G", "5MG..6G"} {
+		_, _, err = ParseRange(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFormatLimit(t *testing.T) {
+	assert.Equal(t, "1000.0T", FormatLimit(1_000_000_000_000_000-1))
+	assert.Equal(t, "1.0P", FormatLimit(1_000_000_000_000_000))
+	assert.Equal(t, "2.5G", FormatLimit(2_500_000_000))
+	assert.Equal(t, "999999", FormatLimit(999_999))
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+)
+
+// LoopAccelerator is a sieve as written by the cycle generator. Candidates are
+// 2^n with n = j*Length + Index[i] for batch j. Residue, when present, holds
+// 2^Index[i] mod 10^Order for each entry in the same order as Index. Older
+// sieve files don't have it.
+type LoopAccelerator struct {
+	Mask      uint64
+	Order     int
+	Length    uint64
+	Leadin    uint64
+	EvenItems int
+	Gain      float64
+	Index     []uint64
+	Residue   []uint64     `json:",omitempty"`
+	Layers    []SieveLayer `json:"-"`
+}
+
+// ReadAccelerator reads a sieve file and checks that its parts are consistent
+// with each other
+func ReadAccelerator(name string) (LoopAccelerator, error) {
+	config := LoopAccelerator{}
+	txt, err := os.ReadFile(name)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(txt, &config)
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", name, err)
+	}
+	switch {
+	case config.Length == 0 || len(config.Index) == 0:
+		return config, fmt.Errorf("%s: sieve is empty", name)
+	case config.Residue != nil && len(config.Residue) != len(config.Index):
+		return config, fmt.Errorf("%s: %d residues for %d indexes", name, len(config.Residue), len(config.Index))
+	}
+	return config, nil
+}
+
+// StepTable finds the gaps between successive entries of the sieve. The last
+// gap takes us from the last entry to the start of the next batch. The distinct
+// gap sizes are returned in ascending order along with the position of each
+// entry's gap in that list.
+func (config *LoopAccelerator) StepTable() ([]uint64, []uint16, error) {
+	gaps := make([]uint64, 0, len(config.Index)+1)
+	c0 := uint64(0)
+	for _, c := range slices.Concat(config.Index, []uint64{config.Length}) {
+		if c < c0 {
+			return nil, nil, fmt.Errorf("sieve index %d follows %d", c, c0)
+		}
+		gaps = append(gaps, c-c0)
+		c0 = c
+	}
+
+	steps := slices.Clone(gaps)
+	slices.Sort(steps)
+	steps = slices.Compact(steps)
+	if len(steps) > math.MaxUint16+1 {
+		return nil, nil, fmt.Errorf("sieve has %d distinct steps, too many to index", len(steps))
+	}
+
+	stepIndex := make([]uint16, len(gaps))
+	for i, gap := range gaps {
+		k, _ := slices.BinarySearch(steps, gap)
+		stepIndex[i] = uint16(k)
+	}
+	return steps, stepIndex, nil
+}
+
+// Gaps returns the differences between successive sieve indexes, treating the
+// sieve as a cycle so that the last gap wraps around from the last index to the
+// first index of the next batch
+func (config *LoopAccelerator) Gaps() []uint64 {
+	gaps := make([]uint64, len(config.Index))
+	for i, k := range config.Index {
+		if i > 0 {
+			gaps[i] = k - config.Index[i-1]
+		} else {
+			gaps[i] = k + config.Length - config.Index[len(config.Index)-1]
+		}
+	}
+	return gaps
+}
+
+// Derive projects the sieve down to a smaller order. The powers of two mod
+// 10^order repeat with a period that divides Length, so each index reduced
+// modulo the smaller cycle length is a candidate for the smaller sieve with the
+// low digits of its residue. Candidates that carry into the smaller number of
+// digits are dropped.
+//
+// Nothing is missed by this. If 2^i mod 10^t has all even digits and doesn't
+// carry, digit t of 2^i is twice digit t of 2^(i-1) and so is even, and that
+// digit of 2^(i-1) takes every value of one parity among the 5 values of i
+// that agree modulo the shorter cycle. At least one of those doesn't carry into
+// t+1 digits, so every entry of a smaller sieve lifts to at least one entry of
+// each larger one.
+func (config *LoopAccelerator) Derive(order int) (LoopAccelerator, error) {
+	if order < 1 || order > config.Order {
+		return LoopAccelerator{}, fmt.Errorf("can't derive a sieve of order %d from one of order %d", order, config.Order)
+	}
+	mask := uint64(10)
+	length := uint64(4)
+	for i := 1; i < order; i++ {
+		mask *= 10
+		length *= 5
+	}
+	if config.Length%length != 0 {
+		return LoopAccelerator{}, fmt.Errorf("cycle length %d of order %d isn't a multiple of %d", config.Length, config.Order, length)
+	}
+
+	residues := map[uint64]uint64{}
+	for i, k := range config.Index {
+		var r uint64
+		if config.Residue != nil {
+			r = config.Residue[i]
+		} else {
+			r = PowMod64(2, k, config.Mask)
+		}
+		// without a carry, 2^(k-1) is half of 2^k in the low digits
+		if (r/2)%mask >= mask/2 {
+			continue
+		}
+		// indexes run from order+1 to order+length, as the cycle generator writes them
+		k = k % length
+		if k <= uint64(order) {
+			k += length
+		}
+		residues[k] = r % mask
+	}
+
+	derived := LoopAccelerator{
+		Mask:   mask,
+		Order:  order,
+		Length: length,
+		Leadin: uint64(order),
+		Index:  make([]uint64, 0, len(residues)),
+	}
+	for k := range residues {
+		derived.Index = append(derived.Index, k)
+	}
+	slices.Sort(derived.Index)
+	derived.Residue = make([]uint64, len(derived.Index))
+	for i, k := range derived.Index {
+		derived.Residue[i] = residues[k]
+	}
+	derived.EvenItems = len(derived.Index)
+	derived.Gain = float64(length) / float64(derived.EvenItems)
+	return derived, nil
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	big, err := ReadAccelerator("../cycle-009.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	for _, name := range []string{"../cycle-001.json", "../cycle-002.json", "../cycle-003.json", "../cycle-006.json"} {
+		want, err := ReadAccelerator(name)
+		if err != nil {
+			t.Skipf("no sieve file %s", name)
+		}
+		derived, err := big.Derive(want.Order)
+		assert.NoError(t, err)
+		assert.Equal(t, want.Mask, derived.Mask)
+		assert.Equal(t, want.Length, derived.Length)
+		assert.Equal(t, want.Leadin, derived.Leadin)
+		assert.Equal(t, want.EvenItems, derived.EvenItems)
+		assert.Equal(t, want.Index, derived.Index, name)
+		for i, k := range derived.Index {
+			assert.Equal(t, PowMod64(2, k, derived.Mask), derived.Residue[i])
+		}
+	}
+
+	// residues in the file give the same result as computing them
+	small, err := big.Derive(6)
+	assert.NoError(t, err)
+	again, err := small.Derive(3)
+	assert.NoError(t, err)
+	direct, err := big.Derive(3)
+	assert.NoError(t, err)
+	assert.Equal(t, direct, again)
+
+	same, err := big.Derive(9)
+	assert.NoError(t, err)
+	assert.Equal(t, big.Index, same.Index)
+
+	_, err = big.Derive(10)
+	assert.Error(t, err)
+	_, err = big.Derive(0)
+	assert.Error(t, err)
+}
+
+func TestStepTable(t *testing.T) {
+	config, err := ReadAccelerator("../cycle-006.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	steps, stepIndex, err := config.StepTable()
+	assert.NoError(t, err)
+	assert.Len(t, stepIndex, len(config.Index)+1)
+	assert.True(t, slices.IsSorted(steps))
+	assert.Equal(t, len(steps), len(slices.Compact(slices.Clone(steps))))
+
+	// adding up the steps gives back every index and then the next batch
+	n := uint64(0)
+	for i, k := range stepIndex {
+		n += steps[k]
+		if i < len(config.Index) {
+			assert.Equal(t, config.Index[i], n)
+		}
+	}
+	assert.Equal(t, config.Length, n)
+
+	// gaps from 1 to 65,536 and a last gap of 1 make as many distinct steps as
+	// a uint16 can index, and one more distinct last gap is too many
+	many := LoopAccelerator{}
+	for gap := uint64(1); gap <= math.MaxUint16+1; gap++ {
+		many.Length += gap
+		many.Index = append(many.Index, many.Length)
+	}
+	many.Length++
+	steps, _, err = many.StepTable()
+	assert.NoError(t, err)
+	assert.Len(t, steps, math.MaxUint16+1)
+	many.Length += math.MaxUint16 + 1
+	_, _, err = many.StepTable()
+	assert.ErrorContains(t, err, "65537 distinct steps")
+
+	bad := LoopAccelerator{Length: 10, Index: []uint64{3, 2}}
+	_, _, err = bad.StepTable()
+	assert.Error(t, err)
+}
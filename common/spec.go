@@ -0,0 +1,121 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunSpec describes a scan so that a campaign of runs can be kept in files
+// rather than in shell history. Each field corresponds to the command line flag
+// of the same name and fields that are left out don't change the flag. Flags
+// given on the command line take precedence over the spec.
+type RunSpec struct {
+	Sieve      []string      `yaml:"sieve" json:"sieve"`
+	Digits     int           `yaml:"digits" json:"digits"`
+	Threads    int           `yaml:"threads" json:"threads"`
+	Ranges     []string      `yaml:"ranges" json:"ranges"`
+	Kernel     string        `yaml:"kernel" json:"kernel"`
+	Lift       *bool         `yaml:"lift" json:"lift"`
+	Out        string        `yaml:"out" json:"out"`
+	Run        string        `yaml:"run" json:"run"`
+	Checkpoint time.Duration `yaml:"checkpoint" json:"checkpoint"`
+	Metrics    string        `yaml:"metrics" json:"metrics"`
+	Verbose    bool          `yaml:"verbose" json:"verbose"`
+}
+
+// specJSON is the JSON form of a RunSpec. The checkpoint interval is written
+// as a string such as "10m" in both YAML and JSON.
+type specJSON struct {
+	RunSpec
+	Checkpoint string `json:"checkpoint"`
+}
+
+// ReadRunSpec reads a run spec from a file. Files ending in .json are read as
+// JSON and anything else as YAML. Unknown fields are errors so that a typo
+// can't silently leave a setting at its default.
+func ReadRunSpec(name string) (RunSpec, error) {
+	txt, err := os.ReadFile(name)
+	if err != nil {
+		return RunSpec{}, err
+	}
+	var spec RunSpec
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		var raw specJSON
+		decoder := json.NewDecoder(bytes.NewReader(txt))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&raw); err != nil {
+			return RunSpec{}, fmt.Errorf("%s: %w", name, err)
+		}
+		spec = raw.RunSpec
+		if raw.Checkpoint != "" {
+			spec.Checkpoint, err = time.ParseDuration(raw.Checkpoint)
+			if err != nil {
+				return RunSpec{}, fmt.Errorf("%s: checkpoint: %w", name, err)
+			}
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(txt))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&spec); err != nil && err != io.EOF {
+			return RunSpec{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return spec, nil
+}
+
+// Apply sets each flag in `fs` that the spec has a value for, except for flags
+// that were given explicitly on the command line. Values are set through the
+// flags themselves so they are checked the same way as typed flags. It is an
+// error for the spec to set something that `fs` has no flag for.
+func (spec RunSpec) Apply(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for _, v := range spec.values() {
+		if explicit[v.name] {
+			continue
+		}
+		if fs.Lookup(v.name) == nil {
+			return fmt.Errorf("run spec sets %s but this program has no -%s flag", v.name, v.name)
+		}
+		if err := fs.Set(v.name, v.value); err != nil {
+			return fmt.Errorf("run spec value %q for %s: %w", v.value, v.name, err)
+		}
+	}
+	return nil
+}
+
+// values lists the settings in the spec as flag names and values in a fixed
+// order, leaving out anything that isn't set
+func (spec RunSpec) values() []struct{ name, value string } {
+	var r []struct{ name, value string }
+	add := func(name, value string, ok bool) {
+		if ok {
+			r = append(r, struct{ name, value string }{name, value})
+		}
+	}
+	add("sieve", strings.Join(spec.Sieve, ","), len(spec.Sieve) > 0)
+	add("digits", strconv.Itoa(spec.Digits), spec.Digits != 0)
+	add("threads", strconv.Itoa(spec.Threads), spec.Threads != 0)
+	add("limit", strings.Join(spec.Ranges, ","), len(spec.Ranges) > 0)
+	add("kernel", spec.Kernel, spec.Kernel != "")
+	if spec.Lift != nil {
+		add("lift", strconv.FormatBool(*spec.Lift), true)
+	}
+	add("out", spec.Out, spec.Out != "")
+	add("run", spec.Run, spec.Run != "")
+	add("checkpoint", spec.Checkpoint.String(), spec.Checkpoint != 0)
+	add("metrics", spec.Metrics, spec.Metrics != "")
+	add("verbose", "true", spec.Verbose)
+	return r
+}
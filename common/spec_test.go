@@ -0,0 +1,85 @@
+package common
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunSpec(t *testing.T) {
+	dir := t.TempDir()
+	yamlSpec := filepath.Join(dir, "spec.yaml")
+	assert.NoError(t, os.WriteFile(yamlSpec, []byte(`
+sieve: [cycle-012.json, cycle-013.json]
+digits: 60
+ranges: [0..10P, 20P..30P]
+lift: false
+checkpoint: 10m
+`), 0666))
+	jsonSpec := filepath.Join(dir, "spec.json")
+	assert.NoError(t, os.WriteFile(jsonSpec, []byte(`{
+  "sieve": ["cycle-012.json", "cycle-013.json"],
+  "digits": 60,
+  "ranges": ["0..10P", "20P..30P"],
+  "lift": false,
+  "checkpoint": "10m"
+}`), 0666))
+
+	for _, name := range []string{yamlSpec, jsonSpec} {
+		spec, err := ReadRunSpec(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, 10*time.Minute, spec.Checkpoint, name)
+
+		fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+		sieve := fs.String("sieve", "cycle-002.json", "")
+		digits := fs.Int("digits", 50, "")
+		limit := fs.String("limit", "10G", "")
+		lift := fs.Bool("lift", true, "")
+		checkpoint := fs.Duration("checkpoint", 0, "")
+		assert.NoError(t, fs.Parse([]string{"-digits", "40"}))
+
+		assert.NoError(t, spec.Apply(fs), name)
+		assert.Equal(t, "cycle-012.json,cycle-013.json", *sieve)
+		assert.Equal(t, 40, *digits, "flags on the command line win")
+		assert.Equal(t, "0..10P,20P..30P", *limit)
+		assert.False(t, *lift)
+		assert.Equal(t, 10*time.Minute, *checkpoint)
+
+		// a program without a flag for a setting can't use the spec
+		fs = flag.NewFlagSet("simple", flag.ContinueOnError)
+		fs.Int("digits", 50, "")
+		assert.Error(t, spec.Apply(fs))
+	}
+}
+
+func TestRunSpecErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"typo.yaml":   "digitz: 40\n",
+		"typo.json":   `{"digitz": 40}`,
+		"bad.json":    `{"checkpoint": "often"}`,
+		"type.yaml":   "digits: many\n",
+		"syntax.json": `{"digits": }`,
+	} {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(file, []byte(body), 0666))
+		_, err := ReadRunSpec(file)
+		assert.Error(t, err, name)
+	}
+	_, err := ReadRunSpec(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.Int("digits", 50, "")
+	assert.Error(t, RunSpec{Threads: 3}.Apply(fs))
+
+	// settings left out of the spec need no flag
+	empty := filepath.Join(dir, "empty.yaml")
+	assert.NoError(t, os.WriteFile(empty, nil, 0666))
+	spec, err := ReadRunSpec(empty)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Apply(fs))
+}
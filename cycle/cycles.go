@@ -1,34 +1,60 @@
 package main
 
 import (
+	"EvenDigits/common"
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"golang.org/x/text/message"
 	"log"
-	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
 )
 
+// maxDigits is the largest order whose cycle can be found with 64-bit
+// arithmetic
+const maxDigits = 18
+
 /*
 Scans for cycles in the low digits of powers of two. Patterns of this
 sort can decimate the search for values of 2^n where all digits are even.
 */
 func main() {
-	p := message.NewPrinter(message.MatchLanguage("en"))
+	minOrder := flag.Int("min", 1, "Smallest number of digits to find the cycle for")
+	maxOrder := flag.Int("max", maxDigits, "Largest number of digits to find the cycle for")
+	exportList := flag.String("export", "1-3,6,9,12-15", `Orders to write sieve files for as a comma separated list of orders and ranges like 12-15, or "all" or "none"`)
+	out := flag.String("out", ".", "Directory for the sieve files")
+	format := flag.String("format", "json", "Format of the sieve files, json or csv")
+	threads := flag.Int("threads", runtime.NumCPU(), "Number of threads to use walking each cycle")
+	verify := flag.Bool("verify", false, "Check the cycle length and leadin with Floyd's algorithm")
+	flag.Parse()
 
-	exports := map[uint64]bool{
-		10:                    true,
-		100:                   true,
-		1000:                  true,
-		1_000_000:             true,
-		1_000_000_000:         true,
-		1_000_000_000_000:     true,
-		10_000_000_000_000:    true,
-		100_000_000_000_000:   true,
-		1_000_000_000_000_000: true,
+	if *minOrder < 1 || *maxOrder > maxDigits || *minOrder > *maxOrder {
+		log.Fatalf("Orders must satisfy 1 <= min <= max <= %d, not %d and %d", maxDigits, *minOrder, *maxOrder)
+	}
+	if *threads < 1 {
+		log.Fatalf("Must use at least one thread, not %d", *threads)
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("Unknown format %q, must be json or csv", *format)
+	}
+	exports, err := parseOrders(*exportList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = common.OutputDir(*out)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	start := uint64(1)
+	p := message.NewPrinter(message.MatchLanguage("en"))
+
 	fmt.Printf("                                                                    gain vs \n")
 	fmt.Printf(
 		"%8s %5s %15s %8s %8s %6s %7s  %s\n",
@@ -41,37 +67,18 @@ func main() {
 		"even",
 		"brute force",
 	)
-	for mask, digits := uint64(10), 1; mask <= 1_000_000_000_000_000; mask, digits = 10*mask, digits+1 {
-		fast := start
-		slow := start
-		i := 0
-		for {
-			fast = (fast * 4) % mask
-			slow = (slow * 2) % mask
-			i++
-			if fast == slow {
-				break
-			}
-		}
-
-		slow = start
-		mu := 0
-		for {
-			fast = (fast * 2) % mask
-			slow = (slow * 2) % mask
-			mu++
-			if fast == slow {
-				break
-			}
-		}
-		// fmt.Printf("found cycle entry %d %d,%d\n", mu, fast, slow)
-
-		n := 0
-		for {
-			fast = (fast * 2) % mask
-			n++
-			if fast == slow {
-				break
+	mask := uint64(1)
+	for i := 1; i < *minOrder; i++ {
+		mask *= 10
+	}
+	for digits := *minOrder; digits <= *maxOrder; digits++ {
+		// each order is found from scratch so starting part way costs nothing
+		mask *= 10
+		mu, n := cycleShape(digits)
+		if *verify {
+			floydMu, floydN := floyd(mask)
+			if floydMu != mu || floydN != n {
+				log.Fatalf("Floyd finds a cycle of length %d after %d steps for %d digits, expected %d after %d", floydN, floydMu, digits, n, mu)
 			}
 		}
 
@@ -82,84 +89,233 @@ func main() {
 			tail[i] = (1 << i) % mask
 		}
 
-		exclusion := true  // are all the tail elements excluded from the cycle?
-		inclusion := false // is the first element after the tail included?
-		allEven := 0
-
-		for i := 0; i < n; i++ {
-			tmp := fast * 2
-			fast = tmp % mask
-			if evenDigits(fast) && tmp == fast {
-				// all even digit and no carry
-				allEven++
+		walk := walkCycle(mask, mu, n, tail, exports[digits], *threads)
+		if exports[digits] {
+			indexes := walk.indexes
+			cycle := walk.cycle
+			output := struct {
+				Mask          uint64
+				Order         int
+				Length        int
+				Leadin        int
+				EvenItems     int
+				Gain          float64
+				StepHistogram map[int]int
+				Cycle         []uint64
+				Index         []int
+				Residue       []uint64
+			}{
+				Mask:          mask,
+				Order:         digits,
+				Length:        n,
+				Leadin:        mu,
+				EvenItems:     len(cycle),
+				Gain:          float64(n) / float64(len(cycle)),
+				StepHistogram: stepHistogram(indexes, n),
+				Cycle:         cycle,
+				Index:         indexes,
+				Residue:       walk.values,
 			}
-			//fmt.Printf("%5d %t vs ", fast, evenDigits(fast))
-			for j := 0; j < len(tail)-1; j++ {
-				//fmt.Printf("%5d %t ", tail[j], fast == tail[j])
-				if fast == tail[j] {
-					exclusion = false
-					break
+
+			var txt []byte
+			if *format == "csv" {
+				txt = sieveCSV(indexes, walk.values)
+			} else {
+				txt, err = json.MarshalIndent(output, "", "  ")
+				if err != nil {
+					log.Fatal(err)
 				}
 			}
-			//fmt.Printf("\n")
-			if fast == tail[mu] {
-				inclusion = true
+			// sieve files are the same for every run so they keep their
+			// names, but written atomically a reader never sees part of one
+			name := fmt.Sprintf("cycle-%03d.%s", digits, *format)
+			err = common.WriteFileAtomic(filepath.Join(*out, name), txt, 0666)
+			if err != nil {
+				log.Fatal(err)
 			}
 		}
+		//fmt.Printf("entered cycle of length %d after %d steps\n", n, mu)
+		_, _ = p.Printf("%8d %5d %15d %8t %8t %6d %7d %10.2f\n", digits, mu, n, walk.inclusion, walk.exclusion, tail[mu-1], walk.allEven, float64(n)/float64(walk.allEven))
+	}
+}
 
-		_, export := exports[mask]
-		if export {
-			indexes := []int{}
-			cycle := []uint64{}
-			for i := 0; i < n; i++ {
-				tmp := fast * 2
-				fast = tmp % mask
-				if evenDigits(fast) && tmp == fast {
-					indexes = append(indexes, i+mu+1)
-					cycle = append(cycle, fast)
-				}
-			}
-			slices.Sort(cycle)
-			output := struct {
-				Mask      uint64
-				Order     int
-				Length    int
-				Leadin    int
-				EvenItems int
-				Gain      float64
-				Cycle     []uint64
-				Index     []int
-			}{
-				Mask:      mask,
-				Order:     digits,
-				Length:    n,
-				Leadin:    mu,
-				EvenItems: len(cycle),
-				Gain:      float64(n) / float64(len(cycle)),
-				Cycle:     cycle,
-				Index:     indexes,
-			}
+// cycleShape returns the leadin and length of the cycle of powers of two mod
+// 10^digits. Modulo 5^digits, 2 is a primitive root so its powers repeat with
+// period 4*5^(digits-1). Modulo 2^digits, powers of two are zero from 2^digits
+// onwards. Together, these mean that 2^digits is the first power in the cycle.
+func cycleShape(digits int) (int, int) {
+	n := 4
+	for i := 1; i < digits; i++ {
+		n *= 5
+	}
+	return digits, n
+}
 
-			f, err := os.OpenFile(fmt.Sprintf("cycle-%03d.json", digits), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-			if err != nil {
-				log.Fatal(err)
+// floyd finds the leadin and length of the cycle of powers of two mod mask the
+// hard way, using Floyd's tortoise and hare algorithm. This takes several
+// passes over the cycle and is only used to check cycleShape.
+func floyd(mask uint64) (int, int) {
+	fast := uint64(1)
+	slow := uint64(1)
+	for {
+		fast = (fast * 4) % mask
+		slow = (slow * 2) % mask
+		if fast == slow {
+			break
+		}
+	}
+
+	slow = 1
+	mu := 0
+	for {
+		fast = (fast * 2) % mask
+		slow = (slow * 2) % mask
+		mu++
+		if fast == slow {
+			break
+		}
+	}
+
+	n := 0
+	for {
+		fast = (fast * 2) % mask
+		n++
+		if fast == slow {
+			break
+		}
+	}
+	return mu, n
+}
+
+// cycleWalk is what is learned by walking the cycle of powers of two
+type cycleWalk struct {
+	exclusion bool // are all the tail elements excluded from the cycle?
+	inclusion bool // is the first element after the tail included?
+	allEven   int
+	indexes   []int    // survivors in order of index
+	values    []uint64 // 2^index mod mask for each survivor
+	cycle     []uint64 // survivor values in ascending order
+}
+
+// walkCycle visits the n elements of the cycle that follow 2^mu mod mask and
+// counts the survivors, those with all even digits and no carry from the
+// previous power. If collect is set, the survivors themselves are kept too.
+//
+// The walk is split into one piece per thread. Each piece starts at a power of
+// two computed directly by modular exponentiation so the pieces don't depend
+// on each other. Pieces are joined in order so the indexes stay in order, and
+// their sorted values are merged.
+func walkCycle(mask uint64, mu, n int, tail []uint64, collect bool, threads int) cycleWalk {
+	pieces := make([]cycleWalk, threads)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func(t int) {
+			defer wg.Done()
+			pieces[t] = walkPiece(mask, mu, n*t/threads, n*(t+1)/threads, tail, collect)
+		}(t)
+	}
+	wg.Wait()
+
+	r := cycleWalk{exclusion: true}
+	for _, piece := range pieces {
+		r.exclusion = r.exclusion && piece.exclusion
+		r.inclusion = r.inclusion || piece.inclusion
+		r.allEven += piece.allEven
+		r.indexes = append(r.indexes, piece.indexes...)
+		r.values = append(r.values, piece.values...)
+		r.cycle = mergeSorted(r.cycle, piece.cycle)
+	}
+	return r
+}
+
+// walkPiece walks the elements of the cycle from index mu+lo+1 through mu+hi
+func walkPiece(mask uint64, mu, lo, hi int, tail []uint64, collect bool) cycleWalk {
+	w := cycleWalk{exclusion: true}
+	fast := common.PowMod64(2, uint64(mu+lo), mask)
+	for i := lo; i < hi; i++ {
+		tmp := fast * 2
+		fast = tmp % mask
+		if evenDigits(fast) && tmp == fast {
+			// all even digit and no carry
+			w.allEven++
+			if collect {
+				w.indexes = append(w.indexes, i+mu+1)
+				w.values = append(w.values, fast)
 			}
-			defer func(f *os.File) {
-				_ = f.Close()
-			}(f)
-			txt, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				log.Fatal(err)
+		}
+		for j := 0; j < len(tail)-1; j++ {
+			if fast == tail[j] {
+				w.exclusion = false
+				break
 			}
-			_, err = f.Write(txt)
+		}
+		if fast == tail[mu] {
+			w.inclusion = true
+		}
+	}
+	w.cycle = slices.Clone(w.values)
+	slices.Sort(w.cycle)
+	return w
+}
+
+// mergeSorted merges two ascending lists
+func mergeSorted(a, b []uint64) []uint64 {
+	r := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] <= b[0] {
+			r, a = append(r, a[0]), a[1:]
+		} else {
+			r, b = append(r, b[0]), b[1:]
+		}
+	}
+	return append(append(r, a...), b...)
+}
+
+// parseOrders converts a list such as "1-3,6,9" into the set of orders it
+// names. The words "all" and "none" are also allowed.
+func parseOrders(list string) (map[int]bool, error) {
+	orders := map[int]bool{}
+	switch list {
+	case "none", "":
+		return orders, nil
+	case "all":
+		list = fmt.Sprintf("1-%d", maxDigits)
+	}
+	for _, item := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+		lo, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("bad order %q in export list", item)
+		}
+		hi := lo
+		if isRange {
+			hi, err = strconv.Atoi(last)
 			if err != nil {
-				log.Fatal(err)
+				return nil, fmt.Errorf("bad order range %q in export list", item)
 			}
-			_ = f.Close()
 		}
-		//fmt.Printf("entered cycle of length %d after %d steps\n", n, mu)
-		_, _ = p.Printf("%8d %5d %15d %8t %8t %6d %7d %10.2f\n", digits, mu, n, inclusion, exclusion, tail[mu-1], allEven, float64(n)/float64(allEven))
+		if lo < 1 || hi > maxDigits || lo > hi {
+			return nil, fmt.Errorf("export orders %q must be an increasing range between 1 and %d", item, maxDigits)
+		}
+		for k := lo; k <= hi; k++ {
+			orders[k] = true
+		}
+	}
+	return orders, nil
+}
+
+// sieveCSV writes each sieve index along with 2^index mod 10^order as CSV.
+// This is handy for analysis but the scanner only reads JSON sieves.
+func sieveCSV(index []int, values []uint64) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	_ = w.Write([]string{"index", "value"})
+	for i, k := range index {
+		_ = w.Write([]string{strconv.Itoa(k), strconv.FormatUint(values[i], 10)})
 	}
+	w.Flush()
+	return b.Bytes()
 }
 
 func evenDigits(x uint64) bool {
@@ -174,3 +330,17 @@ func evenDigits(x uint64) bool {
 	}
 	return even
 }
+
+// stepHistogram counts how often each gap between successive indexes occurs,
+// including the gap that wraps around from the last index to the first.
+func stepHistogram(indexes []int, length int) map[int]int {
+	histogram := map[int]int{}
+	for i, k := range indexes {
+		if i > 0 {
+			histogram[k-indexes[i-1]]++
+		} else {
+			histogram[k+length-indexes[len(indexes)-1]]++
+		}
+	}
+	return histogram
+}
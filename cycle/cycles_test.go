@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestWalkCycle(t *testing.T) {
+	var sieve struct {
+		Mask   uint64
+		Length int
+		Leadin int
+		Cycle  []uint64
+		Index  []int
+	}
+	txt, err := os.ReadFile("../cycle-006.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	assert.NoError(t, json.Unmarshal(txt, &sieve))
+
+	tail := make([]uint64, sieve.Leadin+1)
+	for i := range tail {
+		tail[i] = (1 << i) % sieve.Mask
+	}
+	single := walkCycle(sieve.Mask, sieve.Leadin, sieve.Length, tail, true, 1)
+	assert.Equal(t, sieve.Index, single.indexes)
+	assert.Equal(t, sieve.Cycle, single.cycle)
+	assert.Equal(t, len(sieve.Index), single.allEven)
+	assert.True(t, single.exclusion)
+	assert.True(t, single.inclusion)
+
+	// splitting the walk, even unevenly, changes nothing
+	for _, threads := range []int{2, 7, 64} {
+		assert.Equal(t, single, walkCycle(sieve.Mask, sieve.Leadin, sieve.Length, tail, true, threads), threads)
+	}
+	counted := walkCycle(sieve.Mask, sieve.Leadin, sieve.Length, tail, false, 3)
+	assert.Equal(t, single.allEven, counted.allEven)
+	assert.Empty(t, counted.indexes)
+}
+
+func TestCycleShape(t *testing.T) {
+	mask := uint64(1)
+	for digits := 1; digits <= 7; digits++ {
+		mask *= 10
+		mu, n := floyd(mask)
+		shapeMu, shapeN := cycleShape(digits)
+		assert.Equal(t, mu, shapeMu, digits)
+		assert.Equal(t, n, shapeN, digits)
+	}
+}
+
+func TestParseOrders(t *testing.T) {
+	for list, want := range map[string][]int{
+		"6":          {6},
+		"12-15":      {12, 13, 14, 15},
+		"1-3,6,9":    {1, 2, 3, 6, 9},
+		"9,6,6":      {6, 9},
+		"5-5":        {5},
+		"1-3,2-4":    {1, 2, 3, 4},
+		"18":         {18},
+		"none":       {},
+		"":           {},
+		"all":        {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18},
+		"1-3,12-15,": nil,
+		"3-1":        nil,
+		"3-":         nil,
+		"-3":         nil,
+		"0":          nil,
+		"19":         nil,
+		"17-19":      nil,
+		"x":          nil,
+		"1,,2":       nil,
+	} {
+		orders, err := parseOrders(list)
+		if want == nil {
+			assert.Error(t, err, list)
+			continue
+		}
+		assert.NoError(t, err, list)
+		got := []int{}
+		for k := range orders {
+			got = append(got, k)
+		}
+		assert.ElementsMatch(t, want, got, list)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golang.org/x/text/message"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+/*
+Builds smaller sieves from a larger one by projecting it down to fewer digits.
+This is much faster than walking the smaller cycles again and the results can
+be compared with sieves from the cycle generator as a consistency check or used
+as test fixtures.
+*/
+func main() {
+	order := flag.Int("order", 0, "Number of digits in the derived sieve")
+	out := flag.String("out", "derived", "Directory for the derived sieve")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -order k [options] sieve.json\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *order == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := common.ReadAccelerator(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	derived, err := config.Derive(*order)
+	if err != nil {
+		log.Fatal(err)
+	}
+	txt, err := json.MarshalIndent(newSieveFile(derived), "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := common.OutputDir(*out); err != nil {
+		log.Fatal(err)
+	}
+	name := filepath.Join(*out, fmt.Sprintf("cycle-%03d.json", *order))
+	if err := common.WriteFileAtomic(name, txt, 0666); err != nil {
+		log.Fatal(err)
+	}
+
+	p := message.NewPrinter(message.MatchLanguage("en"))
+	_, _ = p.Printf("%s: order %d, cycle length %d, %d entries from %d in %s\n",
+		name, derived.Order, derived.Length, derived.EvenItems, len(config.Index), flag.Arg(0))
+}
+
+// sieveFile has the same fields in the same order as the files written by the
+// cycle generator, so a derived sieve can be used in place of one of those
+type sieveFile struct {
+	Mask          uint64
+	Order         int
+	Length        uint64
+	Leadin        uint64
+	EvenItems     int
+	Gain          float64
+	StepHistogram map[uint64]int
+	Cycle         []uint64
+	Index         []uint64
+	Residue       []uint64
+}
+
+// newSieveFile fills in the parts of a sieve file that the scanner doesn't
+// read, the histogram of gaps and the residues in ascending order
+func newSieveFile(config common.LoopAccelerator) sieveFile {
+	histogram := map[uint64]int{}
+	for _, gap := range config.Gaps() {
+		histogram[gap]++
+	}
+	cycle := slices.Clone(config.Residue)
+	slices.Sort(cycle)
+	return sieveFile{
+		Mask:          config.Mask,
+		Order:         config.Order,
+		Length:        config.Length,
+		Leadin:        config.Leadin,
+		EvenItems:     config.EvenItems,
+		Gain:          config.Gain,
+		StepHistogram: histogram,
+		Cycle:         cycle,
+		Index:         config.Index,
+		Residue:       config.Residue,
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestSieveFile(t *testing.T) {
+	config, err := common.ReadAccelerator("../cycle-013.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	txt, err := os.ReadFile("../cycle-006.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	var want sieveFile
+	assert.NoError(t, json.Unmarshal(txt, &want))
+
+	derived, err := config.Derive(6)
+	assert.NoError(t, err)
+	got := newSieveFile(derived)
+	assert.Equal(t, want.Mask, got.Mask)
+	assert.Equal(t, want.Order, got.Order)
+	assert.Equal(t, want.Length, got.Length)
+	assert.Equal(t, want.Leadin, got.Leadin)
+	assert.Equal(t, want.EvenItems, got.EvenItems)
+	assert.Equal(t, want.Gain, got.Gain)
+	assert.Equal(t, want.StepHistogram, got.StepHistogram)
+	assert.Equal(t, want.Cycle, got.Cycle)
+	assert.Equal(t, want.Index, got.Index)
+	assert.Equal(t, want.Residue, got.Residue)
+
+	// written out, it is the same file
+	out, err := json.MarshalIndent(got, "", "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, string(txt), string(out))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golang.org/x/text/message"
+	"log"
+	"math"
+	"os"
+	"slices"
+)
+
+/*
+Prints statistics about sieve files such as the distribution of gaps between
+entries and how much a sieve should speed up a search. This answers the
+questions that otherwise need ad hoc scripts over very large JSON files.
+*/
+
+// Gap is one distinct gap between successive sieve entries and how often it
+// occurs. The sieve is treated as a cycle so these are the same gaps as in the
+// StepHistogram written by the cycle generator.
+type Gap struct {
+	Step  uint64
+	Count int
+}
+
+// Stats summarizes a sieve
+type Stats struct {
+	Name          string
+	Order         int
+	Length        uint64
+	Leadin        uint64
+	EvenItems     int
+	Entries       int
+	Expected      float64 // Length / 2^Order
+	Density       float64 // Entries / Length
+	DistinctSteps int
+	ScannerSteps  int // distinct steps including those to and from batch boundaries
+	MinStep       uint64
+	MaxStep       uint64
+	MeanStep      float64
+	Gaps          []Gap
+	Gain          float64 // candidates per test with the sieve alone
+	LiftSurvivors int     // entries surviving the lift filter over 5 batches
+	LiftGain      float64 // candidates per test with the lift filter
+	Residues      string
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "Print the statistics as JSON")
+	top := flag.Int("gaps", 10, "Number of the most common gaps to show, zero for all")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] sieve.json ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var all []Stats
+	for _, name := range flag.Args() {
+		config, err := common.ReadAccelerator(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stats, err := inspect(name, config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		all = append(all, stats)
+	}
+
+	if *asJSON {
+		txt, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(txt))
+		return
+	}
+	p := message.NewPrinter(message.MatchLanguage("en"))
+	for _, stats := range all {
+		report(p, stats, *top)
+	}
+}
+
+// inspect computes the statistics for a sieve
+func inspect(name string, config common.LoopAccelerator) (Stats, error) {
+	scannerSteps, _, err := config.StepTable()
+	if err != nil {
+		return Stats{}, fmt.Errorf("%s: %w", name, err)
+	}
+	counts := map[uint64]int{}
+	for _, gap := range config.Gaps() {
+		counts[gap]++
+	}
+	gaps := make([]Gap, 0, len(counts))
+	for step, count := range counts {
+		gaps = append(gaps, Gap{Step: step, Count: count})
+	}
+	// most common first, ties broken by the smaller gap
+	slices.SortFunc(gaps, func(a, b Gap) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Step, b.Step))
+	})
+
+	entries := len(config.Index)
+	stats := Stats{
+		Name:          name,
+		Order:         config.Order,
+		Length:        config.Length,
+		Leadin:        config.Leadin,
+		EvenItems:     config.EvenItems,
+		Entries:       entries,
+		Expected:      float64(config.Length) / math.Exp2(float64(config.Order)),
+		Density:       float64(entries) / float64(config.Length),
+		DistinctSteps: len(gaps),
+		ScannerSteps:  len(scannerSteps),
+		MinStep:       slices.MinFunc(gaps, byStep).Step,
+		MaxStep:       slices.MaxFunc(gaps, byStep).Step,
+		MeanStep:      float64(config.Length) / float64(entries),
+		Gaps:          gaps,
+		Gain:          float64(config.Length) / float64(entries),
+		Residues:      "absent",
+	}
+
+	lift, err := common.LiftLayer(config)
+	if err == nil {
+		stats.LiftSurvivors = lift.Survivors()
+		stats.LiftGain = float64(lift.Period*config.Length) / float64(stats.LiftSurvivors)
+	}
+
+	if config.Residue != nil {
+		stats.Residues = "correct"
+		for i, k := range config.Index {
+			if config.Residue[i] != common.PowMod64(2, k, config.Mask) {
+				stats.Residues = fmt.Sprintf("wrong for index %d", k)
+				break
+			}
+		}
+	}
+	return stats, nil
+}
+
+func byStep(a, b Gap) int {
+	return cmp.Compare(a.Step, b.Step)
+}
+
+// report prints the statistics for one sieve
+func report(p *message.Printer, stats Stats, top int) {
+	_, _ = p.Printf("%s\n", stats.Name)
+	_, _ = p.Printf("  order %d, cycle length %d, leadin %d\n", stats.Order, stats.Length, stats.Leadin)
+	_, _ = p.Printf("  entries            %12d (EvenItems %d)\n", stats.Entries, stats.EvenItems)
+	_, _ = p.Printf("  Length/2^Order     %12.1f (entries are %.3f of that)\n", stats.Expected, float64(stats.Entries)/stats.Expected)
+	_, _ = p.Printf("  distinct steps     %12d (%d in the scanner's table)\n", stats.DistinctSteps, stats.ScannerSteps)
+	_, _ = p.Printf("  steps              %12d min, %d max, %.1f mean\n", stats.MinStep, stats.MaxStep, stats.MeanStep)
+	_, _ = p.Printf("  gain over brute    %12.2f\n", stats.Gain)
+	if stats.LiftSurvivors > 0 {
+		_, _ = p.Printf("  gain with -lift    %12.2f (%d of %d candidates survive)\n", stats.LiftGain, stats.LiftSurvivors, 5*stats.Entries)
+	}
+	_, _ = p.Printf("  residues           %12s\n", stats.Residues)
+
+	gaps := stats.Gaps
+	if top > 0 && top < len(gaps) {
+		_, _ = p.Printf("  %d most common gaps of %d\n", top, len(gaps))
+		gaps = gaps[:top]
+	} else {
+		_, _ = p.Printf("  gaps\n")
+	}
+	for _, gap := range gaps {
+		_, _ = p.Printf("    %12d %10d %6.2f%%\n", gap.Step, gap.Count, 100*float64(gap.Count)/float64(stats.Entries))
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	config, err := common.ReadAccelerator("../cycle-002.json")
+	if err != nil {
+		t.Skip("no sieve file")
+	}
+	stats, err := inspect("cycle-002.json", config)
+	assert.NoError(t, err)
+
+	// this matches the StepHistogram in the main README
+	assert.Equal(t, []Gap{{4, 2}, {1, 1}, {3, 1}, {8, 1}}, stats.Gaps)
+	assert.Equal(t, 4, stats.DistinctSteps)
+	assert.Equal(t, uint64(1), stats.MinStep)
+	assert.Equal(t, uint64(8), stats.MaxStep)
+	assert.Equal(t, 4.0, stats.Gain)
+	assert.Equal(t, 5.0, stats.Expected)
+	assert.Equal(t, []uint64{8, 64, 24, 48, 88}, config.Residue)
+	assert.Equal(t, "correct", stats.Residues)
+
+	residues := config.Residue
+	config.Residue = nil
+	stats, err = inspect("cycle-002.json", config)
+	assert.NoError(t, err)
+	assert.Equal(t, "absent", stats.Residues)
+	config.Residue = residues
+	config.Residue[2] = 26
+	stats, err = inspect("cycle-002.json", config)
+	assert.NoError(t, err)
+	assert.Equal(t, "wrong for index 10", stats.Residues)
+}
@@ -0,0 +1,130 @@
+package mp
+
+import (
+	"math"
+	"math/bits"
+)
+
+// IsZero returns true if `a` is zero
+func (a UInt[L, D]) IsZero() bool {
+	for i := 0; i < len(a.Content); i++ {
+		if a.Content[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BitLen returns the number of bits needed to represent `a`. The bit length of
+// zero is 0.
+func (a UInt[L, D]) BitLen() int {
+	for i := len(a.Content) - 1; i >= 0; i-- {
+		if a.Content[i] != 0 {
+			return 32*i + bits.Len64(a.Content[i])
+		}
+	}
+	return 0
+}
+
+// Add destructively adds `b` to `a` and returns the carry out of the top of
+// `a`, either 0 or 1. If the carry is 1, `a` holds the sum modulo 2^Bits().
+func (a *UInt[L, D]) Add(b UInt[L, D]) uint64 {
+	carry := uint64(0)
+	for i := 0; i < len(a.Content); i++ {
+		u := a.Content[i] + b.Content[i] + carry
+		a.Content[i] = u & math.MaxUint32
+		carry = u >> 32
+	}
+	return carry
+}
+
+// Sub destructively subtracts `b` from `a` and returns the borrow out of the
+// top of `a`, either 0 or 1. If the borrow is 1, `b` was larger than `a` and
+// `a` holds the difference modulo 2^Bits().
+func (a *UInt[L, D]) Sub(b UInt[L, D]) uint64 {
+	borrow := uint64(0)
+	for i := 0; i < len(a.Content); i++ {
+		// both operands are less than 2^32 so a negative difference
+		// shows up as the top bit of u
+		u := a.Content[i] - b.Content[i] - borrow
+		a.Content[i] = u & math.MaxUint32
+		borrow = u >> 63
+	}
+	return borrow
+}
+
+// Lsh destructively shifts `a` left by `n` bits. Bits shifted past the top of
+// `a` are lost.
+func (a *UInt[L, D]) Lsh(n uint) {
+	words := int(n / 32)
+	shift := n % 32
+	for i := len(a.Content) - 1; i >= 0; i-- {
+		u := uint64(0)
+		if k := i - words; k >= 0 {
+			u = a.Content[k] << shift
+			if k > 0 {
+				u |= a.Content[k-1] >> (32 - shift)
+			}
+		}
+		a.Content[i] = u & math.MaxUint32
+	}
+}
+
+// Rsh destructively shifts `a` right by `n` bits
+func (a *UInt[L, D]) Rsh(n uint) {
+	words := int(n / 32)
+	shift := n % 32
+	for i := 0; i < len(a.Content); i++ {
+		u := uint64(0)
+		if k := i + words; k < len(a.Content) {
+			u = a.Content[k] >> shift
+			if k+1 < len(a.Content) {
+				u |= a.Content[k+1] << (32 - shift)
+			}
+		}
+		a.Content[i] = u & math.MaxUint32
+	}
+}
+
+// DivMod destructively divides `a` by `b` and returns the remainder. This
+// is plain binary long division so it is much slower than Mod and is meant
+// for setup and verification rather than inner loops. Like big.Int, it panics
+// if `b` is zero.
+func (a *UInt[L, D]) DivMod(b UInt[L, D]) UInt[L, D] {
+	if b.IsZero() {
+		panic("division by zero")
+	}
+	q := UInt[L, D]{}
+	r := UInt[L, D]{}
+	top := len(r.Content) - 1
+	for i := a.BitLen() - 1; i >= 0; i-- {
+		// if the top bit of r is about to be shifted out, then 2r+1 is
+		// certainly at least b and the wrapped subtraction below is exact
+		overflow := r.Content[top] >> 31
+		r.Lsh(1)
+		r.Content[0] |= (a.Content[i/32] >> (i % 32)) & 1
+		if overflow != 0 || r.Cmp(b) >= 0 {
+			r.Sub(b)
+			q.Content[i/32] |= 1 << (i % 32)
+		}
+	}
+	*a = q
+	return r
+}
+
+// DivModWord divides `a` by `b` and returns the remainder. Unlike DivModSmall,
+// any nonzero 64-bit divisor is allowed. The limbs of `a` must be normalized on
+// entry and are normalized on return.
+func (a *UInt[L, D]) DivModWord(b uint64) uint64 {
+	if b == 0 {
+		panic("division by zero")
+	}
+	rem := uint64(0)
+	for i := len(a.Content) - 1; i >= 0; i-- {
+		// rem < b so the quotient of this 96-bit value fits in 32 bits
+		q, r := bits.Div64(rem>>32, rem<<32|a.Content[i], b)
+		a.Content[i] = q
+		rem = r
+	}
+	return rem
+}
@@ -0,0 +1,139 @@
+package mp
+
+import (
+	"math"
+	"math/big"
+)
+
+// maxLimbs is the number of elements in the widest of the Limbs types. It
+// sizes the scratch space used by Barrett reduction.
+const maxLimbs = 12
+
+// Barrett reduces products modulo a fixed mask using a reciprocal of the mask
+// that is computed once. Each reduction then takes two partial multiplications
+// and at most two subtractions instead of the trial division done by Mod256.
+// A single Barrett can be shared by any number of reductions, including
+// concurrent ones, since it is never modified after it is made.
+//
+// Values to be reduced must be less than 2^(64k) where the mask has k 32-bit
+// elements. This always holds for the product of two values less than the
+// mask.
+type Barrett[L, D Limbs] struct {
+	mask UInt[L, D]
+	k    int                  // number of significant elements in mask
+	mu   [maxLimbs + 2]uint64 // floor(2^(64k) / mask)
+}
+
+// NewBarrett computes the reciprocal needed to reduce values modulo `mask`
+func NewBarrett[L, D Limbs](mask UInt[L, D]) *Barrett[L, D] {
+	if mask.IsZero() {
+		panic("mp: Barrett reduction modulo zero")
+	}
+	r := &Barrett[L, D]{mask: mask, k: (mask.BitLen() + 31) / 32}
+	mu := new(big.Int).Lsh(big.NewInt(1), uint(64*r.k))
+	mu.Quo(mu, mask.Big())
+	low := big.NewInt(math.MaxUint32)
+	for i := 0; mu.Sign() > 0; i++ {
+		r.mu[i] = new(big.Int).And(mu, low).Uint64()
+		mu.Rsh(mu, 32)
+	}
+	return r
+}
+
+// Reduce returns `x mod mask`
+func (r *Barrett[L, D]) Reduce(x Wide[L, D]) UInt[L, D] {
+	k := r.k
+
+	// q = floor(x / M^(k-1)) * mu where M = 2^32. Its elements from k+1
+	// upwards are an estimate of x / mask that is at most 2 too small.
+	var q [2*maxLimbs + 4]uint64
+	for i := 0; i <= k; i++ {
+		xi := x.content[k-1+i]
+		if xi == 0 {
+			continue
+		}
+		carry := uint64(0)
+		for j := 0; j < k+2; j++ {
+			// the largest value is (M-1)^2 + 2(M-1) which just fits
+			u := xi*r.mu[j] + q[i+j] + carry
+			q[i+j] = u & math.MaxUint32
+			carry = u >> 32
+		}
+		q[i+k+2] = carry
+	}
+	estimate := q[k+1:]
+
+	// only the low k+1 elements of x - estimate * mask are needed since the
+	// difference is less than 3 * mask
+	var t [maxLimbs + 1]uint64
+	for i := 0; i <= k; i++ {
+		qi := estimate[i]
+		if qi == 0 {
+			continue
+		}
+		carry := uint64(0)
+		for j := 0; j < k && i+j <= k; j++ {
+			u := qi*r.mask.Content[j] + t[i+j] + carry
+			t[i+j] = u & math.MaxUint32
+			carry = u >> 32
+		}
+		if i == 0 {
+			t[k] = carry
+		}
+	}
+	borrow := uint64(0)
+	for i := 0; i <= k; i++ {
+		u := x.content[i] - t[i] - borrow
+		t[i] = u & math.MaxUint32
+		borrow = u >> 63
+	}
+
+	for r.atLeastMask(&t) {
+		borrow = 0
+		for i := 0; i <= k; i++ {
+			m := uint64(0)
+			if i < k {
+				m = r.mask.Content[i]
+			}
+			u := t[i] - m - borrow
+			t[i] = u & math.MaxUint32
+			borrow = u >> 63
+		}
+	}
+
+	z := UInt[L, D]{}
+	for i := 0; i < k; i++ {
+		z.Content[i] = t[i]
+	}
+	return z
+}
+
+// atLeastMask compares the k+1 element value `t` with the mask
+func (r *Barrett[L, D]) atLeastMask(t *[maxLimbs + 1]uint64) bool {
+	if t[r.k] != 0 {
+		return true
+	}
+	for i := r.k - 1; i >= 0; i-- {
+		if t[i] != r.mask.Content[i] {
+			return t[i] > r.mask.Content[i]
+		}
+	}
+	return true
+}
+
+// MulMod returns `a * b mod mask`. Both `a` and `b` must be less than the mask.
+func (r *Barrett[L, D]) MulMod(a, b UInt[L, D]) UInt[L, D] {
+	return r.Reduce(a.Mul(b))
+}
+
+// MulModBatch sets `out[i]` to `base * table[i] mod mask` for every element of
+// `table`. The products are worked out one after another, but none of them
+// depends on another and all of them share the reciprocal held by `r`, so
+// none of them has to divide. Both `base` and the table must be reduced and
+// `out` must be at least as long as `table`.
+func MulModBatch[L, D Limbs](base UInt[L, D], table []UInt[L, D], r *Barrett[L, D], out []UInt[L, D]) {
+	out = out[:len(table)]
+	for i := range table {
+		out[i] = r.Reduce(base.Mul(table[i]))
+	}
+}
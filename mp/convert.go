@@ -0,0 +1,154 @@
+package mp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// chunk is the largest power of ten that fits in a uint64 along with the
+// number of digits it covers. Decimal conversion divides by this so that it
+// only needs one pass over the limbs per 18 digits rather than per digit.
+const (
+	chunk       = 1_000_000_000_000_000_000
+	chunkDigits = 18
+)
+
+// Big returns the value of `a` as a big.Int
+func (a UInt[L, D]) Big() *big.Int {
+	buf := make([]byte, 4*len(a.Content))
+	for i := 0; i < len(a.Content); i++ {
+		k := len(buf) - 4*i
+		u := a.Content[i]
+		buf[k-1] = byte(u)
+		buf[k-2] = byte(u >> 8)
+		buf[k-3] = byte(u >> 16)
+		buf[k-4] = byte(u >> 24)
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+// SetBig sets `a` to the value of `x` and returns true. If `x` is negative or
+// too large for `a`, false is returned and `a` is left unchanged.
+func (a *UInt[L, D]) SetBig(x *big.Int) bool {
+	if x.Sign() < 0 || x.BitLen() > a.Bits() {
+		return false
+	}
+	buf := x.FillBytes(make([]byte, 4*len(a.Content)))
+	for i := 0; i < len(a.Content); i++ {
+		k := len(buf) - 4*i
+		a.Content[i] = uint64(buf[k-1]) | uint64(buf[k-2])<<8 | uint64(buf[k-3])<<16 | uint64(buf[k-4])<<24
+	}
+	return true
+}
+
+// SetString sets `a` to the value of `s` interpreted as a decimal integer. As
+// with big.Int, an optional leading sign is allowed. The result is `a` and a
+// flag indicating success. If `s` is not a valid decimal number or is negative
+// or too large for `a`, the flag is false and `a` is left unchanged.
+func (a *UInt[L, D]) SetString(s string) (*UInt[L, D], bool) {
+	negative := false
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return a, false
+	}
+	r := UInt[L, D]{}
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return a, false
+		}
+		if !r.MulSmallChecked(10) || r.Add(NewUInt[L, D](uint64(c-'0'))) != 0 {
+			return a, false
+		}
+	}
+	if negative && !r.IsZero() {
+		return a, false
+	}
+	*a = r
+	return a, true
+}
+
+// String returns the decimal representation of `a`
+func (a UInt[L, D]) String() string {
+	var chunks []uint64
+	for !a.IsZero() {
+		chunks = append(chunks, a.DivModWord(chunk))
+	}
+	if len(chunks) == 0 {
+		return "0"
+	}
+	last := len(chunks) - 1
+	r := strconv.AppendUint(make([]byte, 0, chunkDigits*len(chunks)), chunks[last], 10)
+	for i := last - 1; i >= 0; i-- {
+		r = r[:len(r)+chunkDigits]
+		putDigits(r[len(r)-chunkDigits:], chunks[i])
+	}
+	return string(r)
+}
+
+// Digits returns exactly `width` decimal digits, the low digits of `a` padded
+// on the left with zeros. For a value reduced mod 10^width, such as the
+// residues the scanner works with, the leading zeros are real digits of the
+// full number and are significant. Any digits above `width` are dropped.
+func (a UInt[L, D]) Digits(width int) string {
+	r := make([]byte, width)
+	for k := width; k > 0; k -= chunkDigits {
+		putDigits(r[max(0, k-chunkDigits):k], a.DivModWord(chunk))
+	}
+	return string(r)
+}
+
+// putDigits fills `dst` with the low decimal digits of `x`, zero padded
+func putDigits(dst []byte, x uint64) {
+	for i := len(dst) - 1; i >= 0; i-- {
+		dst[i] = byte('0' + x%10)
+		x /= 10
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler using decimal digits
+func (a UInt[L, D]) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text must be a
+// decimal integer that fits in `a`.
+func (a *UInt[L, D]) UnmarshalText(text []byte) error {
+	if _, ok := a.SetString(string(text)); !ok {
+		return fmt.Errorf("mp: can't convert %q to a %d bit integer", text, a.Bits())
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The value is written as a quoted
+// decimal string since most JSON readers would round a bare number this large.
+func (a UInt[L, D]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Either a quoted decimal string
+// or a bare integer is accepted.
+func (a *UInt[L, D]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	return a.UnmarshalText(data)
+}
+
+// Format implements fmt.Formatter and supports the same verbs and flags as
+// big.Int including %d, %x, %X, %o and %b as well as width and padding.
+func (a UInt[L, D]) Format(s fmt.State, verb rune) {
+	a.Big().Format(s, verb)
+}
@@ -0,0 +1,132 @@
+package mp
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// MaxParityDigits is the largest number of digits a Parity can describe. This
+// is more than the widest integer type can hold.
+const MaxParityDigits = 128
+
+// Parity records which of the low decimal digits of a value are odd. Digit 0 is
+// the least significant. Extracting the digits is the expensive part, so it is
+// done once in a single chunked pass, and the queries on the result are cheap
+// bit operations that any number of predicates can share.
+type Parity struct {
+	odd   [2]uint64
+	width int
+}
+
+// Parity returns the parity of the low `width` decimal digits of `a`. Digits
+// above the value's most significant digit count as zeros and so are even.
+func (a UInt[L, D]) Parity(width int) Parity {
+	return a.ParityAbove(0, width)
+}
+
+// ParityAbove is like Parity, but the low `known` digits are taken to be even
+// without being extracted. This saves work when those digits are already known,
+// as they are for the scanner's candidates.
+func (a UInt[L, D]) ParityAbove(known, width int) Parity {
+	return a.parity(known, width, false)
+}
+
+// FirstOddAbove returns the same as ParityAbove(known, width).FirstOdd(), but
+// stops extracting digits as soon as it finds an odd one. Most values have an
+// odd digit near the bottom, so this is much faster than a full Parity.
+func (a UInt[L, D]) FirstOddAbove(known, width int) int {
+	return a.parity(known, width, true).FirstOdd()
+}
+
+// parity extracts the parity of digits `known` through `width-1`, 18 digits at
+// a time. If `stop` is set, it returns as soon as an odd digit is found.
+func (a UInt[L, D]) parity(known, width int, stop bool) Parity {
+	if width < 0 || width > MaxParityDigits || known < 0 || known > width {
+		panic(fmt.Sprintf("mp: can't take the parity of digits %d to %d", known, width))
+	}
+	p := Parity{width: width}
+	for k := known; k > 0; k -= chunkDigits {
+		a.DivModWord(chunkPowers[min(k, chunkDigits)])
+	}
+	for k := known; k < width && !a.IsZero(); k += chunkDigits {
+		x := a.DivModWord(chunk)
+		for i := k; i < min(width, k+chunkDigits); i++ {
+			odd := x % 10 & 1
+			p.odd[i/64] |= odd << (i % 64)
+			if stop && odd != 0 {
+				return p
+			}
+			x /= 10
+		}
+	}
+	return p
+}
+
+// chunkPowers holds 10^n for every n up to chunkDigits
+var chunkPowers = func() (r [chunkDigits + 1]uint64) {
+	r[0] = 1
+	for i := 1; i < len(r); i++ {
+		r[i] = 10 * r[i-1]
+	}
+	return r
+}()
+
+// Width returns the number of digits described by `p`
+func (p Parity) Width() int {
+	return p.width
+}
+
+// IsOdd returns true if digit `i` is odd
+func (p Parity) IsOdd(i int) bool {
+	return p.odd[i/64]&(1<<(i%64)) != 0
+}
+
+// AllEven returns true if no digit is odd
+func (p Parity) AllEven() bool {
+	return p.odd[0]|p.odd[1] == 0
+}
+
+// FirstOdd returns the position of the least significant odd digit or -1 if
+// all of the digits are even. This is the same as the scanner's digit check.
+func (p Parity) FirstOdd() int {
+	switch {
+	case p.odd[0] != 0:
+		return bits.TrailingZeros64(p.odd[0])
+	case p.odd[1] != 0:
+		return 64 + bits.TrailingZeros64(p.odd[1])
+	}
+	return -1
+}
+
+// CountOdd returns the number of odd digits
+func (p Parity) CountOdd() int {
+	return bits.OnesCount64(p.odd[0]) + bits.OnesCount64(p.odd[1])
+}
+
+// CountEven returns the number of even digits
+func (p Parity) CountEven() int {
+	return p.width - p.CountOdd()
+}
+
+// OddPositions returns the positions of all odd digits in increasing order
+func (p Parity) OddPositions() []int {
+	r := make([]int, 0, p.CountOdd())
+	for w := 0; w < len(p.odd); w++ {
+		for x := p.odd[w]; x != 0; x &= x - 1 {
+			r = append(r, 64*w+bits.TrailingZeros64(x))
+		}
+	}
+	return r
+}
+
+// LongestEvenRun returns the length of the longest run of consecutive even
+// digits
+func (p Parity) LongestEvenRun() int {
+	longest := 0
+	start := 0
+	for _, i := range append(p.OddPositions(), p.width) {
+		longest = max(longest, i-start)
+		start = i + 1
+	}
+	return longest
+}
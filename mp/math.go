@@ -2,24 +2,61 @@ package mp
 
 import (
 	"math"
-	"slices"
 )
 
-// UInt256 is a 256-bit integer. These structures are entirely static and thus are
+// Limbs are the arrays that can hold the content of a fixed width integer.
+// Each element holds 32 bits of the value so that products of two elements
+// fit in a uint64.
+type Limbs interface {
+	[4]uint64 | [6]uint64 | [8]uint64 | [12]uint64 | [16]uint64 | [24]uint64
+}
+
+// UInt is a fixed width integer whose value is held in `L`. The second type
+// parameter `D` must be an array twice as long as `L` and is used to hold the
+// full product of two values. These structures are entirely static and thus are
 // subject to copy semantics. Importantly, they can be allocated on the stack to
 // avoid GC pressure.
-type UInt256 struct {
-	Content [8]uint64
+type UInt[L, D Limbs] struct {
+	Content L
 }
 
-// UInt512 is a 512-bit integer. Only limited operations are supported since these
-// are only used as temporary values in the implementation of ModMul for UInt256.
-type UInt512 struct {
-	content [16]uint64
+// Wide is a double width integer. Only limited operations are supported since
+// these are only used as temporary values in the implementation of MulMod.
+type Wide[L, D Limbs] struct {
+	content D
+}
+
+// UInt128 is a 128-bit integer
+type UInt128 = UInt[[4]uint64, [8]uint64]
+
+// UInt192 is a 192-bit integer
+type UInt192 = UInt[[6]uint64, [12]uint64]
+
+// UInt256 is a 256-bit integer
+type UInt256 = UInt[[8]uint64, [16]uint64]
+
+// UInt384 is a 384-bit integer
+type UInt384 = UInt[[12]uint64, [24]uint64]
+
+// UInt512 is a 512-bit integer. It is the product of two UInt256 values.
+type UInt512 = Wide[[8]uint64, [16]uint64]
+
+// NewUInt returns a fixed width integer with the value x
+func NewUInt[L, D Limbs](x uint64) UInt[L, D] {
+	r := UInt[L, D]{}
+	r.Content[0] = x & math.MaxUint32
+	r.Content[1] = x >> 32
+	return r
+}
+
+// Bits returns the number of bits that a value of this type can hold. See also
+// MaxDigits.
+func (a UInt[L, D]) Bits() int {
+	return 32 * len(a.Content)
 }
 
 // Cmp returns -1, 0 or 1 if a < b, a == b or a > b, respectively.
-func (a UInt256) Cmp(b UInt256) int {
+func (a UInt[L, D]) Cmp(b UInt[L, D]) int {
 	for i := len(a.Content) - 1; i >= 0; i-- {
 		if a.Content[i] > b.Content[i] {
 			return 1
@@ -32,8 +69,8 @@ func (a UInt256) Cmp(b UInt256) int {
 }
 
 // Cmp256 returns -1, 0 or 1 if a < b, a == b or a > b, respectively but `a`
-// is UInt512 and `b` is UInt256.
-func (a UInt512) Cmp256(b UInt256) int {
+// is a double width value. The name is historical, `b` can be any width.
+func (a Wide[L, D]) Cmp256(b UInt[L, D]) int {
 	for i := len(a.content) - 1; i >= len(b.Content); i-- {
 		if a.content[i] > 0 {
 			return 1
@@ -54,7 +91,7 @@ func (a UInt512) Cmp256(b UInt256) int {
 // MulSmall destructively multiplies a large value by a small one. The
 // destination value must be normalized and will be normalized again upon return.
 // The multiplier should be limited to `[0...math.MaxUint32]`
-func (a *UInt256) MulSmall(b uint64) {
+func (a *UInt[L, D]) MulSmall(b uint64) {
 	if b > math.MaxUint32 {
 		panic("b > math.MaxUint16")
 	}
@@ -66,10 +103,42 @@ func (a *UInt256) MulSmall(b uint64) {
 	}
 }
 
+// MulSmallChecked is like MulSmall, but reports whether the result fit. If it
+// didn't, `a` is left unchanged and false is returned.
+func (a *UInt[L, D]) MulSmallChecked(b uint64) bool {
+	if b > math.MaxUint32 {
+		panic("b > math.MaxUint32")
+	}
+	r := *a
+	carry := uint64(0)
+	for i := 0; i < len(r.Content); i++ {
+		tmp := r.Content[i]*b + carry
+		r.Content[i] = tmp & math.MaxUint32
+		carry = tmp >> 32
+	}
+	if carry != 0 {
+		return false
+	}
+	*a = r
+	return true
+}
+
+// MaxDigits returns the largest `d` such that `10^d` can be held in this type.
+// A modulus no larger than that can be used with MulMod since the product of
+// two values less than the modulus fits in the double width intermediate.
+func (a UInt[L, D]) MaxDigits() int {
+	z := NewUInt[L, D](1)
+	d := 0
+	for z.MulSmallChecked(10) {
+		d++
+	}
+	return d
+}
+
 // AddSmall adds a 64bit quantity to a larger value which is destructively
 // modified. The destination does not have to be normalized before calling
 // this, but will be normalized afterwards.
-func (a *UInt256) AddSmall(b uint64) {
+func (a *UInt[L, D]) AddSmall(b uint64) {
 	if b > math.MaxUint32 {
 		panic("b > math.MaxUint16")
 	}
@@ -83,7 +152,7 @@ func (a *UInt256) AddSmall(b uint64) {
 // DivModSmall divides a large value by a small one and returns
 // the remainder. The divisor should be less than `math.MaxUint32`
 // and the destination should be normalized on entry.
-func (a *UInt256) DivModSmall(b uint64) uint64 {
+func (a *UInt[L, D]) DivModSmall(b uint64) uint64 {
 	rem := uint64(0)
 	for i := len(a.Content) - 1; i >= 0; i-- {
 		rem = rem << 32
@@ -116,7 +185,7 @@ func (a *UInt256) DivModSmall(b uint64) uint64 {
 	return rem
 }
 
-func (a *UInt256) Mod(b UInt256) {
+func (a *UInt[L, D]) Mod(b UInt[L, D]) {
 	// j is last non-zero element of b
 	j := len(b.Content) - 1
 	for ; j >= 0; j-- {
@@ -131,7 +200,7 @@ func (a *UInt256) Mod(b UInt256) {
 			i--
 			continue
 		}
-		if i == j && a.Cmp(b) <= 0 {
+		if i == j && a.Cmp(b) < 0 {
 			// our work here is done
 			break
 		}
@@ -143,13 +212,14 @@ func (a *UInt256) Mod(b UInt256) {
 		)
 
 		if j > 0 {
-			if a.Content[i] < b.Content[j] {
-				ax = (a.Content[i] << 32) + a.Content[i-1]
+			ax = (a.Content[i] << 32) + a.Content[i-1]
+			bx = (b.Content[j] << 32) + b.Content[j-1]
+			if a.Content[i] < b.Content[j] || (i > j && ax <= bx) {
+				// b doesn't fit under the top two elements of a so
+				// shift one element less and only use the top of b
 				bx = b.Content[j]
 				offset = i - j - 1
 			} else {
-				ax = (a.Content[i] << 32) + a.Content[i-1]
-				bx = (b.Content[j] << 32) + b.Content[j-1]
 				offset = i - j
 			}
 		} else {
@@ -163,7 +233,9 @@ func (a *UInt256) Mod(b UInt256) {
 			bx = b.Content[j]
 		}
 
-		m = ax / (bx + 1)
+		if ax > bx {
+			m = ax / (bx + 1)
+		}
 		if m == 0 {
 			// this happens if the difference between a and b is only in lower bits
 			// so that ax == bx
@@ -171,7 +243,7 @@ func (a *UInt256) Mod(b UInt256) {
 		}
 
 		// tmp = m * M^offset * b where M = 2^32
-		tmp := UInt256{}
+		tmp := UInt[L, D]{}
 		carry := uint64(0)
 		for i := offset; i < len(tmp.Content); i++ {
 			u := b.Content[i-offset]*m + carry
@@ -191,7 +263,9 @@ func (a *UInt256) Mod(b UInt256) {
 	}
 }
 
-func (a *UInt512) Mod256(b UInt256) {
+// Mod256 reduces a double width value modulo `b`. The name is historical, `b`
+// can be any width.
+func (a *Wide[L, D]) Mod256(b UInt[L, D]) {
 	// j is last non-zero element of b
 	j := len(b.Content) - 1
 	for ; j >= 0; j-- {
@@ -206,7 +280,7 @@ func (a *UInt512) Mod256(b UInt256) {
 			i--
 			continue
 		}
-		if i == j && a.Cmp256(b) <= 0 {
+		if i == j && a.Cmp256(b) < 0 {
 			// our work here is done
 			break
 		}
@@ -218,13 +292,14 @@ func (a *UInt512) Mod256(b UInt256) {
 		)
 
 		if j > 0 {
-			if a.content[i] < b.Content[j] {
-				ax = (a.content[i] << 32) + a.content[i-1]
+			ax = (a.content[i] << 32) + a.content[i-1]
+			bx = (b.Content[j] << 32) + b.Content[j-1]
+			if a.content[i] < b.Content[j] || (i > j && ax <= bx) {
+				// b doesn't fit under the top two elements of a so
+				// shift one element less and only use the top of b
 				bx = b.Content[j]
 				offset = i - j - 1
 			} else {
-				ax = (a.content[i] << 32) + a.content[i-1]
-				bx = (b.Content[j] << 32) + b.Content[j-1]
 				offset = i - j
 			}
 		} else {
@@ -238,7 +313,9 @@ func (a *UInt512) Mod256(b UInt256) {
 			bx = b.Content[j]
 		}
 
-		m = ax / (bx + 1)
+		if ax > bx {
+			m = ax / (bx + 1)
+		}
 		if m == 0 {
 			// this happens if the difference between a and b is only in lower bits
 			// so that ax == bx
@@ -246,7 +323,7 @@ func (a *UInt512) Mod256(b UInt256) {
 		}
 
 		// tmp = m * M^offset * b where M = 2^32
-		tmp := UInt512{}
+		tmp := Wide[L, D]{}
 		carry := uint64(0)
 		for i := offset; i < len(tmp.content); i++ {
 			u := carry
@@ -273,7 +350,7 @@ func (a *UInt512) Mod256(b UInt256) {
 	}
 }
 
-func (a UInt512) Cmp512(b UInt512) int {
+func (a Wide[L, D]) Cmp512(b Wide[L, D]) int {
 	for i := len(a.content) - 1; i >= 0; i-- {
 		if a.content[i] > b.content[i] {
 			return 1
@@ -285,17 +362,19 @@ func (a UInt512) Cmp512(b UInt512) int {
 	return 0
 }
 
-func (a UInt256) Mul(b UInt256) UInt512 {
-	r := UInt512{}
-	for i, ax := range a.Content {
-		for j, bx := range b.Content {
+func (a UInt[L, D]) Mul(b UInt[L, D]) Wide[L, D] {
+	r := Wide[L, D]{}
+	for i := 0; i < len(a.Content); i++ {
+		ax := a.Content[i]
+		for j := 0; j < len(b.Content); j++ {
 			// this will fit (just) because
 			// math.MaxUint32 * math.MaxUint32 + math.MaxUint32 = math.MaxUint64
-			r.content[i+j] += ax * bx
+			r.content[i+j] += ax * b.Content[j]
 		}
 		// loop invariant: c0 <= math.MaxUint32 + 2
 		c0 := uint64(0)
-		for k, rx := range r.content {
+		for k := 0; k < len(r.content); k++ {
+			rx := r.content[k]
 			u0 := rx >> 32
 			// max value here is 2 * math.MaxUint32 + 2 = 2^34
 			u1 := (rx & math.MaxUint32) + c0
@@ -306,7 +385,7 @@ func (a UInt256) Mul(b UInt256) UInt512 {
 	return r
 }
 
-func (a *UInt256) MulMod(b, mask UInt256) {
+func (a *UInt[L, D]) MulMod(b, mask UInt[L, D]) {
 	z := a.Mul(b)
 	z.Mod256(mask)
 	for i := 0; i < len(a.Content); i++ {
@@ -314,25 +393,22 @@ func (a *UInt256) MulMod(b, mask UInt256) {
 	}
 }
 
-func (a *UInt256) Pow256(n, mask UInt256) {
+// Pow256 sets `a` to `a^n mod mask`. The name is historical, any width works.
+func (a *UInt[L, D]) Pow256(n, mask UInt[L, D]) {
 	m := *a
-	r := UInt256{[8]uint64{1}}
-	i := 0
-	bit := 0
-	for i < len(n.Content) {
-		if n.Content[i] == 0 {
-			i++
-			bit = 0
-			continue
-		}
-		if n.Content[i]&(1<<bit) != 0 {
-			r.MulMod(m, mask)
-		}
-		m.MulMod(m, mask)
-		bit++
-		if bit == 32 {
-			i++
-			bit = 0
+	r := NewUInt[L, D](1)
+	top := len(n.Content) - 1
+	for top >= 0 && n.Content[top] == 0 {
+		top--
+	}
+	for i := 0; i <= top; i++ {
+		// m has to be squared for every bit, even in elements of n that
+		// are zero, but there is no need to go past the highest set bit
+		for bit := 0; bit < 32 && (i < top || n.Content[i]>>bit != 0); bit++ {
+			if n.Content[i]&(1<<bit) != 0 {
+				r.MulMod(m, mask)
+			}
+			m.MulMod(m, mask)
 		}
 	}
 	*a = r
@@ -340,10 +416,10 @@ func (a *UInt256) Pow256(n, mask UInt256) {
 
 // PowerTable creates a table of $a^{2^n}$ that helps accelerate the
 // computation of powers of $a$ with `PowByTable`
-func PowerTable(a, mask UInt256) []UInt256 {
-	r := make([]UInt256, 256)
+func PowerTable[L, D Limbs](a, mask UInt[L, D]) []UInt[L, D] {
+	r := make([]UInt[L, D], a.Bits())
 	z := a
-	for i := 0; i < 256; i++ {
+	for i := 0; i < len(r); i++ {
 		r[i] = z
 		z.MulMod(z, mask)
 	}
@@ -352,8 +428,8 @@ func PowerTable(a, mask UInt256) []UInt256 {
 
 // PowByTable computes the $n$-th power of `table[0]`.
 // The `table` should the output of a call to `PowerTable`
-func PowByTable(table []UInt256, n, mask UInt256) UInt256 {
-	r := UInt256{[8]uint64{1}}
+func PowByTable[L, D Limbs](table []UInt[L, D], n, mask UInt[L, D]) UInt[L, D] {
+	r := NewUInt[L, D](1)
 	i := 0
 	bit := 0
 	for i < len(n.Content) {
@@ -373,17 +449,3 @@ func PowByTable(table []UInt256, n, mask UInt256) UInt256 {
 	}
 	return r
 }
-
-func (a UInt256) String() string {
-	r := make([]byte, 0)
-	zero := UInt256{}
-	for a.Cmp(zero) > 0 {
-		d := a.DivModSmall(10)
-		r = append(r, byte(d)+'0')
-	}
-	if len(r) == 0 {
-		return "0"
-	}
-	slices.Reverse(r)
-	return string(r)
-}
@@ -1,10 +1,15 @@
 package mp
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"math"
+	"math/big"
 	"math/rand/v2"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -52,6 +57,28 @@ func TestInt320_MulSmall(t *testing.T) {
 	assert.Equal(t, t2&math.MaxUint32, a.Content[2])
 }
 
+func TestMulSmallChecked(t *testing.T) {
+	a := UInt128{[4]uint64{1}}
+	for i := 0; i < 38; i++ {
+		assert.True(t, a.MulSmallChecked(10))
+	}
+	ax := a
+	assert.False(t, a.MulSmallChecked(10))
+	assert.Equal(t, ax, a)
+	assert.Equal(t, "100000000000000000000000000000000000000", a.String())
+
+	b := UInt256{[8]uint64{math.MaxUint32, math.MaxUint32, 0, 0, 0, 0, 0, 1 << 31}}
+	assert.False(t, b.MulSmallChecked(2))
+	assert.True(t, b.MulSmallChecked(1))
+}
+
+func TestMaxDigits(t *testing.T) {
+	assert.Equal(t, 38, UInt128{}.MaxDigits())
+	assert.Equal(t, 57, UInt192{}.MaxDigits())
+	assert.Equal(t, 77, UInt256{}.MaxDigits())
+	assert.Equal(t, 115, UInt384{}.MaxDigits())
+}
+
 func TestInt320_DivRemSmall2(t *testing.T) {
 	a := UInt256{}
 	a.AddSmall(uint64(5003))
@@ -196,6 +223,25 @@ func Test_Mod4(t *testing.T) {
 	assert.Equal(t, 0, a.Cmp(aModB))
 }
 
+func Test_ModEqualTop(t *testing.T) {
+	// the top elements of a and b are equal, but the next one down in a is
+	// smaller so b can't be subtracted at the same alignment
+	a := UInt256{[8]uint64{0, 0, 0, 1, 3}}
+	b := UInt256{[8]uint64{1, 2, 3}}
+	ref := new(big.Int).Mod(a.Big(), b.Big())
+	a.Mod(b)
+	assertBig(t, ref, a)
+
+	w := UInt512{[16]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3}}
+	ref = new(big.Int).Mod(UInt256{[8]uint64{0, 1, 3}}.Big(), b.Big())
+	ref.Lsh(ref, 32*8)
+	ref.Mod(ref, b.Big())
+	w.Mod256(b)
+	r := UInt256{}
+	assert.True(t, r.SetBig(ref))
+	assert.Equal(t, 0, w.Cmp256(r))
+}
+
 func Test_Mod256_0(t *testing.T) {
 	a := pi100
 	b := e70
@@ -288,6 +334,19 @@ func Test_PowMod(t *testing.T) {
 	assert.Equal(t, 0, a.Cmp(pow))
 }
 
+func Test_PowZeroElement(t *testing.T) {
+	mask := UInt256{[8]uint64{1}}
+	for i := 0; i < 55; i++ {
+		mask.MulSmall(10)
+	}
+	// the exponent has an element that is zero below one that isn't
+	n := UInt256{[8]uint64{5, 0, 3}}
+	a := UInt256{[8]uint64{2}}
+	a.Pow256(n, mask)
+	ref := new(big.Int).Exp(big.NewInt(2), n.Big(), mask.Big())
+	assertBig(t, ref, a)
+}
+
 func Test_PowByTable(t *testing.T) {
 	setbit := func(z *UInt256, bit int) {
 		i := bit / 32
@@ -312,7 +371,9 @@ func Test_PowByTable(t *testing.T) {
 		//k := 5
 		j := MinNRand(8, 200)
 		k := MinNRand(8, 200)
-		if i == j || i == k {
+		// n only gets one bit if j == k but z1 would be multiplied by
+		// table[j] twice, so the bits must all be different
+		if i == j || i == k || j == k {
 			continue
 		}
 		z1 := table[i]
@@ -356,3 +417,754 @@ func Test_String(t *testing.T) {
 	z.Pow256(UInt256{[8]uint64{2000}}, mask)
 	assert.Equal(t, "25175435528800822842770817965453762184851149029376", z.String())
 }
+
+func Test_MulModBatch(t *testing.T) {
+	mask := UInt256{[8]uint64{1}}
+	for i := 0; i < 50; i++ {
+		mask.MulSmall(10)
+	}
+	table := PowerTable(UInt256{[8]uint64{2}}, mask)[:100]
+	base := pi70
+	base.Mod(mask)
+
+	out := make([]UInt256, len(table))
+	MulModBatch(base, table, NewBarrett(mask), out)
+	for i, x := range table {
+		z := base
+		z.MulMod(x, mask)
+		assert.Equal(t, z, out[i])
+	}
+}
+
+// assertBig checks that `a` has the same value as `ref`
+func assertBig[L, D Limbs](t *testing.T, ref *big.Int, a UInt[L, D]) {
+	t.Helper()
+	assert.Equal(t, ref.String(), a.Big().String(), "%d bits", a.Bits())
+}
+
+// fuzzValue builds a value from as many bytes of fuzzer input as the type can
+// hold along with the same value as a big.Int
+func fuzzValue[L, D Limbs](data []byte) (UInt[L, D], *big.Int) {
+	r := UInt[L, D]{}
+	x := new(big.Int).SetBytes(data[:min(len(data), r.Bits()/8)])
+	r.SetBig(x)
+	return r, x
+}
+
+// fuzzer holds the fuzz checks for one width of integer. The checks are
+// methods so that each fuzz target can run the same input through every width
+// in `fuzzers`. The generic code paths depend on the number of limbs, so
+// checking only one width would miss bugs in the others.
+type fuzzer[L, D Limbs] struct{}
+
+// fuzzers has a fuzzer for each width that the scanner can pick
+var fuzzers = []interface {
+	addSub(t *testing.T, x, y []byte)
+	shift(t *testing.T, x []byte, n uint)
+	divMod(t *testing.T, x, y []byte)
+	convert(t *testing.T, s string)
+	mod(t *testing.T, x, y []byte)
+	mod256(t *testing.T, x, y []byte)
+	mul(t *testing.T, x, y []byte)
+	mulMod(t *testing.T, x, y, m []byte)
+	barrett(t *testing.T, x, y, m []byte)
+	pow256(t *testing.T, x, n, m []byte)
+	powByTable(t *testing.T, n, m []byte)
+	divModSmall(t *testing.T, x []byte, d uint32)
+	str(t *testing.T, x []byte)
+	divModWord(t *testing.T, x []byte, d uint64)
+	digits(t *testing.T, x []byte, w uint8)
+	parity(t *testing.T, x []byte, w uint8)
+	pow2Mod(t *testing.T, n, m []byte)
+}{
+	fuzzer[[4]uint64, [8]uint64]{},
+	fuzzer[[6]uint64, [12]uint64]{},
+	fuzzer[[8]uint64, [16]uint64]{},
+	fuzzer[[12]uint64, [24]uint64]{},
+}
+
+// addSeeds adds operands that sit on limb boundaries and force long carries
+func addSeeds(f *testing.F) {
+	f.Add([]byte{}, []byte{1})
+	f.Add([]byte{1}, []byte{})
+	for _, n := range []int{16, 24, 32, 48} {
+		ones := bytes.Repeat([]byte{0xff}, n)
+		f.Add(ones, []byte{1})
+		f.Add(ones, ones)
+	}
+	f.Add([]byte{1, 0, 0, 0, 0}, []byte{0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{1, 0, 0, 0, 0, 0, 0, 0, 0}, []byte{1})
+	f.Add(pi70.Big().Bytes(), e70.Big().Bytes())
+}
+
+func FuzzAddSub(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, x, y []byte) {
+		for _, width := range fuzzers {
+			width.addSub(t, x, y)
+		}
+	})
+}
+
+func (fuzzer[L, D]) addSub(t *testing.T, x, y []byte) {
+	a, ax := fuzzValue[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(a.Bits()))
+
+	sum := a
+	carry := sum.Add(b)
+	ref := new(big.Int).Add(ax, bx)
+	assert.Equal(t, ref.Cmp(modulus) >= 0, carry == 1)
+	assertBig(t, ref.Mod(ref, modulus), sum)
+
+	diff := a
+	borrow := diff.Sub(b)
+	ref = new(big.Int).Sub(ax, bx)
+	assert.Equal(t, ref.Sign() < 0, borrow == 1)
+	assertBig(t, ref.Mod(ref, modulus), diff)
+}
+
+func FuzzShift(f *testing.F) {
+	f.Add([]byte{1}, uint(0))
+	f.Add([]byte{1}, uint(31))
+	f.Add([]byte{1}, uint(32))
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff}, uint(33))
+	for _, n := range []uint{127, 128, 191, 192, 255, 256, 383, 384} {
+		f.Add(bytes.Repeat([]byte{0xff}, 48), n)
+	}
+	f.Fuzz(func(t *testing.T, x []byte, n uint) {
+		for _, width := range fuzzers {
+			width.shift(t, x, n)
+		}
+	})
+}
+
+func (fuzzer[L, D]) shift(t *testing.T, x []byte, n uint) {
+	n = n % 450
+	a, ax := fuzzValue[L, D](x)
+	assert.Equal(t, ax.BitLen(), a.BitLen())
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(a.Bits()))
+
+	left := a
+	left.Lsh(n)
+	ref := new(big.Int).Lsh(ax, n)
+	assertBig(t, ref.Mod(ref, modulus), left)
+
+	right := a
+	right.Rsh(n)
+	assertBig(t, new(big.Int).Rsh(ax, n), right)
+}
+
+func FuzzDivMod(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, x, y []byte) {
+		for _, width := range fuzzers {
+			width.divMod(t, x, y)
+		}
+	})
+}
+
+func (fuzzer[L, D]) divMod(t *testing.T, x, y []byte) {
+	a, ax := fuzzValue[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	if bx.Sign() == 0 {
+		assert.Panics(t, func() { a.DivMod(b) })
+		return
+	}
+	q := a
+	r := q.DivMod(b)
+	qx, rx := new(big.Int).QuoRem(ax, bx, new(big.Int))
+	assertBig(t, qx, q)
+	assertBig(t, rx, r)
+}
+
+func FuzzConvert(f *testing.F) {
+	f.Add("0")
+	f.Add("+17")
+	f.Add("4294967296")
+	f.Add(pi70.String())
+	f.Add(strings.Repeat("9", 38))
+	f.Add(strings.Repeat("9", 39))
+	f.Add(strings.Repeat("9", 78))
+	f.Add(strings.Repeat("9", 116))
+	f.Add("12x")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, width := range fuzzers {
+			width.convert(t, s)
+		}
+	})
+}
+
+func (fuzzer[L, D]) convert(t *testing.T, s string) {
+	ref, ok := new(big.Int).SetString(s, 10)
+	seven := NewUInt[L, D](7)
+	fits := ok && ref.Sign() >= 0 && ref.BitLen() <= seven.Bits()
+
+	a := seven
+	_, success := a.SetString(s)
+	assert.Equal(t, fits, success)
+	if !fits {
+		assert.Equal(t, seven, a)
+		return
+	}
+	assertBig(t, ref, a)
+	assert.Equal(t, ref.String(), a.String())
+
+	b := UInt[L, D]{}
+	assert.True(t, b.SetBig(ref))
+	assert.Equal(t, a, b)
+
+	txt, err := json.Marshal(a)
+	assert.NoError(t, err)
+	c := UInt[L, D]{}
+	assert.NoError(t, json.Unmarshal(txt, &c))
+	assert.Equal(t, a, c)
+}
+
+func TestTextAndJSON(t *testing.T) {
+	type report struct {
+		Residue UInt256
+		Tail    *UInt128 `json:",omitempty"`
+	}
+	r := report{Residue: NewUInt[[8]uint64, [16]uint64](1 << 40)}
+	txt, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Residue":"1099511627776"}`, string(txt))
+
+	var back report
+	assert.NoError(t, json.Unmarshal(txt, &back))
+	assert.Equal(t, r, back)
+
+	// bare numbers too large for a float64 are still read exactly
+	assert.NoError(t, json.Unmarshal([]byte(`{"Residue": 314159265358979323846264338327950288419716939937510}`), &back))
+	assert.Equal(t, "314159265358979323846264338327950288419716939937510", back.Residue.String())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Residue": "-3"}`), &back))
+	assert.Error(t, json.Unmarshal([]byte(`{"Residue": 1.5}`), &back))
+	assert.Error(t, json.Unmarshal([]byte(`{"Tail": "`+strings.Repeat("9", 39)+`"}`), &back))
+
+	var a UInt192
+	assert.NoError(t, a.UnmarshalText([]byte("4294967296")))
+	assert.Equal(t, uint64(1), a.Content[1])
+	assert.Error(t, a.UnmarshalText([]byte("0x10")))
+}
+
+func TestFormat(t *testing.T) {
+	a := NewUInt[[8]uint64, [16]uint64](0xbeef)
+	a.Lsh(100)
+	ref := a.Big()
+	for _, format := range []string{"%d", "%v", "%s", "%x", "%X", "%#x", "%o", "%b", "%60d", "%-60d|", "%060d", "%+d", "%50x"} {
+		assert.Equal(t, fmt.Sprintf(format, ref), fmt.Sprintf(format, a), format)
+	}
+	assert.Equal(t, "0000000042", fmt.Sprintf("%010d", NewUInt[[4]uint64, [8]uint64](42)))
+}
+
+// boundaryValues are operands that sit on limb boundaries or that force carries
+// to propagate across many limbs. For each width there is the largest value,
+// the top bit alone and a large power of ten.
+func boundaryValues() [][]byte {
+	values := [][]byte{
+		{1},
+		{0xff, 0xff, 0xff, 0xff},
+		{1, 0, 0, 0, 0},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{1, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		append([]byte{1}, bytes.Repeat([]byte{0}, 16)...),
+		bytes.Repeat([]byte{0xff}, 28),
+		pi70.Big().Bytes(),
+		e70.Big().Bytes(),
+	}
+	for _, n := range []int{16, 24, 32, 48} {
+		values = append(values,
+			bytes.Repeat([]byte{0xff}, n),
+			append([]byte{0x80}, bytes.Repeat([]byte{0}, n-1)...))
+	}
+	for _, digits := range []int64{38, 55, 57, 77, 115} {
+		values = append(values, new(big.Int).Exp(big.NewInt(10), big.NewInt(digits), nil).Bytes())
+	}
+	return values
+}
+
+// addPairSeeds adds every pair of boundary values to the seed corpus
+func addPairSeeds(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, y := range boundaryValues() {
+			f.Add(x, y)
+		}
+	}
+}
+
+// fuzzWide builds a double width value from as many bytes of fuzzer input as
+// it can hold
+func fuzzWide[L, D Limbs](data []byte) (Wide[L, D], *big.Int) {
+	r := Wide[L, D]{}
+	x := new(big.Int).SetBytes(data[:min(len(data), 4*len(r.content))])
+	z := new(big.Int).Set(x)
+	low := big.NewInt(math.MaxUint32)
+	for i := 0; i < len(r.content); i++ {
+		r.content[i] = new(big.Int).And(z, low).Uint64()
+		z.Rsh(z, 32)
+	}
+	return r, x
+}
+
+// wideBig converts a double width value to a big.Int
+func wideBig[L, D Limbs](a Wide[L, D]) *big.Int {
+	r := new(big.Int)
+	for i := len(a.content) - 1; i >= 0; i-- {
+		r.Lsh(r, 32)
+		r.Or(r, new(big.Int).SetUint64(a.content[i]))
+	}
+	return r
+}
+
+func FuzzMod(f *testing.F) {
+	addPairSeeds(f)
+	f.Fuzz(func(t *testing.T, x, y []byte) {
+		for _, width := range fuzzers {
+			width.mod(t, x, y)
+		}
+	})
+}
+
+func (fuzzer[L, D]) mod(t *testing.T, x, y []byte) {
+	a, ax := fuzzValue[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	if bx.Sign() == 0 {
+		return
+	}
+	a.Mod(b)
+	assertBig(t, new(big.Int).Mod(ax, bx), a)
+}
+
+func FuzzMod256(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, y := range boundaryValues() {
+			f.Add(append(slices.Clone(x), x...), y)
+			f.Add(append(slices.Clone(x), bytes.Repeat([]byte{0}, len(x))...), y)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x, y []byte) {
+		for _, width := range fuzzers {
+			width.mod256(t, x, y)
+		}
+	})
+}
+
+func (fuzzer[L, D]) mod256(t *testing.T, x, y []byte) {
+	a, ax := fuzzWide[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	if bx.Sign() == 0 {
+		return
+	}
+	a.Mod256(b)
+	assert.Equal(t, new(big.Int).Mod(ax, bx).String(), wideBig(a).String(), "%d bits", b.Bits())
+}
+
+func FuzzMul(f *testing.F) {
+	addPairSeeds(f)
+	f.Fuzz(func(t *testing.T, x, y []byte) {
+		for _, width := range fuzzers {
+			width.mul(t, x, y)
+		}
+	})
+}
+
+func (fuzzer[L, D]) mul(t *testing.T, x, y []byte) {
+	a, ax := fuzzValue[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	assert.Equal(t, new(big.Int).Mul(ax, bx).String(), wideBig(a.Mul(b)).String(), "%d bits", a.Bits())
+}
+
+func FuzzMulMod(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, m := range boundaryValues() {
+			f.Add(x, x, m)
+			f.Add(x, pi70.Big().Bytes(), m)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x, y, m []byte) {
+		for _, width := range fuzzers {
+			width.mulMod(t, x, y, m)
+		}
+	})
+}
+
+func (fuzzer[L, D]) mulMod(t *testing.T, x, y, m []byte) {
+	a, ax := fuzzValue[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	mask, mx := fuzzValue[L, D](m)
+	if mx.Sign() == 0 {
+		return
+	}
+	a.MulMod(b, mask)
+	ref := new(big.Int).Mul(ax, bx)
+	assertBig(t, ref.Mod(ref, mx), a)
+}
+
+func FuzzBarrett(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, m := range boundaryValues() {
+			f.Add(x, x, m)
+			f.Add(x, pi70.Big().Bytes(), m)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x, y, m []byte) {
+		for _, width := range fuzzers {
+			width.barrett(t, x, y, m)
+		}
+	})
+}
+
+func (fuzzer[L, D]) barrett(t *testing.T, x, y, m []byte) {
+	a, ax := fuzzValue[L, D](x)
+	b, bx := fuzzValue[L, D](y)
+	mask, mx := fuzzValue[L, D](m)
+	if mx.Sign() == 0 {
+		assert.Panics(t, func() { NewBarrett(mask) })
+		return
+	}
+	// Barrett reduction needs reduced operands
+	a.Mod(mask)
+	b.Mod(mask)
+	ref := new(big.Int).Mul(ax.Mod(ax, mx), bx.Mod(bx, mx))
+	assertBig(t, ref.Mod(ref, mx), NewBarrett(mask).MulMod(a, b))
+}
+
+func FuzzPow256(f *testing.F) {
+	for _, m := range boundaryValues() {
+		for _, n := range boundaryValues() {
+			f.Add([]byte{2}, n, m)
+		}
+		f.Add(pi70.Big().Bytes(), []byte{}, m)
+		f.Add(e70.Big().Bytes(), []byte{1, 0, 0, 0, 0, 0, 0, 0, 5}, m)
+	}
+	f.Fuzz(func(t *testing.T, x, n, m []byte) {
+		for _, width := range fuzzers {
+			width.pow256(t, x, n, m)
+		}
+	})
+}
+
+func (fuzzer[L, D]) pow256(t *testing.T, x, n, m []byte) {
+	a, ax := fuzzValue[L, D](x)
+	e, ex := fuzzValue[L, D](n)
+	mask, mx := fuzzValue[L, D](m)
+	if mx.Sign() == 0 || mx.Cmp(big.NewInt(1)) == 0 {
+		// big.Int gives 0 for any power mod 1, but a^0 is 1 here
+		return
+	}
+	a.Pow256(e, mask)
+	assertBig(t, new(big.Int).Exp(ax, ex, mx), a)
+}
+
+func FuzzPowByTable(f *testing.F) {
+	addPairSeeds(f)
+	f.Fuzz(func(t *testing.T, n, m []byte) {
+		for _, width := range fuzzers {
+			width.powByTable(t, n, m)
+		}
+	})
+}
+
+func (fuzzer[L, D]) powByTable(t *testing.T, n, m []byte) {
+	e, ex := fuzzValue[L, D](n)
+	mask, mx := fuzzValue[L, D](m)
+	if mx.Cmp(big.NewInt(1)) <= 0 {
+		return
+	}
+	table := PowerTable(NewUInt[L, D](2), mask)
+	assertBig(t, new(big.Int).Exp(big.NewInt(2), ex, mx), PowByTable(table, e, mask))
+}
+
+func FuzzDivModSmall(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, d := range []uint32{1, 2, 10, 1000, 65535, 65536, math.MaxUint32 - 1, math.MaxUint32} {
+			f.Add(x, d)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x []byte, d uint32) {
+		for _, width := range fuzzers {
+			width.divModSmall(t, x, d)
+		}
+	})
+}
+
+func (fuzzer[L, D]) divModSmall(t *testing.T, x []byte, d uint32) {
+	if d == 0 {
+		return
+	}
+	a, ax := fuzzValue[L, D](x)
+	r := a.DivModSmall(uint64(d))
+	q, rx := new(big.Int).QuoRem(ax, big.NewInt(int64(d)), new(big.Int))
+	assertBig(t, q, a)
+	assert.Equal(t, rx.Uint64(), r)
+}
+
+func FuzzString(f *testing.F) {
+	for _, x := range boundaryValues() {
+		f.Add(x)
+	}
+	f.Fuzz(func(t *testing.T, x []byte) {
+		for _, width := range fuzzers {
+			width.str(t, x)
+		}
+	})
+}
+
+func (fuzzer[L, D]) str(t *testing.T, x []byte) {
+	a, ax := fuzzValue[L, D](x)
+	assert.Equal(t, ax.String(), a.String())
+}
+
+func FuzzDivModWord(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, d := range []uint64{1, 10, math.MaxUint32, 1 << 32, 1_000_000_000_000_000_000, math.MaxUint64} {
+			f.Add(x, d)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x []byte, d uint64) {
+		for _, width := range fuzzers {
+			width.divModWord(t, x, d)
+		}
+	})
+}
+
+func (fuzzer[L, D]) divModWord(t *testing.T, x []byte, d uint64) {
+	if d == 0 {
+		return
+	}
+	a, ax := fuzzValue[L, D](x)
+	r := a.DivModWord(d)
+	q, rx := new(big.Int).QuoRem(ax, new(big.Int).SetUint64(d), new(big.Int))
+	assertBig(t, q, a)
+	assert.Equal(t, rx.Uint64(), r)
+	for i := 0; i < len(a.Content); i++ {
+		assert.Less(t, a.Content[i], uint64(1)<<32, "limbs should stay normalized")
+	}
+}
+
+func FuzzDigits(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, w := range []uint8{0, 1, 17, 18, 19, 38, 50, 78, 90} {
+			f.Add(x, w)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x []byte, w uint8) {
+		for _, width := range fuzzers {
+			width.digits(t, x, w)
+		}
+	})
+}
+
+func (fuzzer[L, D]) digits(t *testing.T, x []byte, w uint8) {
+	a, ax := fuzzValue[L, D](x)
+	width := int(w % 120)
+	tail := new(big.Int).Mod(ax, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(width)), nil))
+	want := fmt.Sprintf("%0*d", width, tail)
+	if width == 0 {
+		want = ""
+	}
+	assert.Equal(t, want, a.Digits(width))
+}
+
+func FuzzParity(f *testing.F) {
+	for _, x := range boundaryValues() {
+		for _, w := range []uint8{0, 1, 18, 38, 50, 64, 65, 90, 128} {
+			f.Add(x, w)
+		}
+	}
+	f.Fuzz(func(t *testing.T, x []byte, w uint8) {
+		for _, width := range fuzzers {
+			width.parity(t, x, w)
+		}
+	})
+}
+
+func (fuzzer[L, D]) parity(t *testing.T, x []byte, w uint8) {
+	a, _ := fuzzValue[L, D](x)
+	width := int(w) % (MaxParityDigits + 1)
+	digits := a.Digits(width)
+	p := a.Parity(width)
+
+	// the reference works from the digit string, least significant first
+	odd := []int{}
+	run, longest := 0, 0
+	for i := 0; i < width; i++ {
+		if (digits[width-1-i]-'0')%2 == 1 {
+			odd = append(odd, i)
+			run = 0
+		} else {
+			run++
+			longest = max(longest, run)
+		}
+		assert.Equal(t, (digits[width-1-i]-'0')%2 == 1, p.IsOdd(i))
+	}
+	first := -1
+	if len(odd) > 0 {
+		first = odd[0]
+	}
+	assert.Equal(t, width, p.Width())
+	assert.Equal(t, odd, p.OddPositions())
+	assert.Equal(t, first, p.FirstOdd())
+	assert.Equal(t, len(odd) == 0, p.AllEven())
+	assert.Equal(t, len(odd), p.CountOdd())
+	assert.Equal(t, width-len(odd), p.CountEven())
+	assert.Equal(t, longest, p.LongestEvenRun())
+
+	// skipping known digits is the same as treating them as even
+	for _, known := range []int{0, min(1, width), width / 3, width} {
+		above := a.ParityAbove(known, width)
+		want := []int{}
+		for _, i := range odd {
+			if i >= known {
+				want = append(want, i)
+			}
+		}
+		assert.Equal(t, want, above.OddPositions(), "known %d", known)
+		assert.Equal(t, above.FirstOdd(), a.FirstOddAbove(known, width), "known %d", known)
+	}
+}
+
+func TestParity(t *testing.T) {
+	// 2^89 = 618970019642690137449562112
+	z := NewUInt[[8]uint64, [16]uint64](1)
+	z.Lsh(89)
+	p := z.Parity(30)
+	assert.Equal(t, 1, p.FirstOdd())
+	assert.Equal(t, []int{1, 2, 5, 6, 9, 10, 11, 13, 18, 19, 22, 23, 25}, p.OddPositions())
+	assert.Equal(t, 17, p.CountEven())
+	// the run 6000 at the top includes three leading zeros
+	assert.Equal(t, 4, p.LongestEvenRun())
+	assert.Panics(t, func() { z.Parity(MaxParityDigits + 1) })
+}
+
+func TestDigits(t *testing.T) {
+	// 2^89 = 618970019642690137449562112 and the middle zeros are significant
+	z := NewUInt[[8]uint64, [16]uint64](1)
+	z.Lsh(89)
+	assert.Equal(t, "00618970019642690137449562112", z.Digits(29))
+	assert.Equal(t, "0137449562112", z.Digits(13))
+	assert.Equal(t, "618970019642690137449562112", z.String())
+	assert.Equal(t, "0000", UInt128{}.Digits(4))
+}
+
+// tenTo returns 10^digits
+func tenTo[L, D Limbs](digits int) UInt[L, D] {
+	r := NewUInt[L, D](1)
+	for i := 0; i < digits; i++ {
+		r.MulSmall(10)
+	}
+	return r
+}
+
+// benchmarkMulMod repeatedly multiplies by 2^1000 modulo 10^digits which is
+// the same pattern as the scanner's inner loop
+func benchmarkMulMod[L, D Limbs](b *testing.B, digits int) {
+	mask := tenTo[L, D](digits)
+	bump := NewUInt[L, D](2)
+	bump.Pow256(NewUInt[L, D](1000), mask)
+	z := NewUInt[L, D](1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.MulMod(bump, mask)
+	}
+}
+
+func BenchmarkMulMod(b *testing.B) {
+	b.Run("UInt128/digits=20", func(b *testing.B) { benchmarkMulMod[[4]uint64, [8]uint64](b, 20) })
+	b.Run("UInt128/digits=38", func(b *testing.B) { benchmarkMulMod[[4]uint64, [8]uint64](b, 38) })
+	b.Run("UInt192/digits=55", func(b *testing.B) { benchmarkMulMod[[6]uint64, [12]uint64](b, 55) })
+	b.Run("UInt256/digits=50", func(b *testing.B) { benchmarkMulMod[[8]uint64, [16]uint64](b, 50) })
+	b.Run("UInt256/digits=77", func(b *testing.B) { benchmarkMulMod[[8]uint64, [16]uint64](b, 77) })
+	b.Run("UInt384/digits=100", func(b *testing.B) { benchmarkMulMod[[12]uint64, [24]uint64](b, 100) })
+}
+
+// benchmarkReduce compares reducing independent products with Mod256 and with
+// a shared Barrett reciprocal, the two ways the table kernel could do it
+func benchmarkReduce[L, D Limbs](b *testing.B, digits int) {
+	mask := tenTo[L, D](digits)
+	table := PowerTable(NewUInt[L, D](2), mask)
+	base := NewUInt[L, D](3)
+	base.Pow256(NewUInt[L, D](1000), mask)
+	out := make([]UInt[L, D], len(table))
+	b.Run("Mod256", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for k := range table {
+				out[k] = base
+				out[k].MulMod(table[k], mask)
+			}
+		}
+	})
+	b.Run("Barrett", func(b *testing.B) {
+		r := NewBarrett(mask)
+		for i := 0; i < b.N; i++ {
+			MulModBatch(base, table, r, out)
+		}
+	})
+}
+
+func BenchmarkMulModBatch(b *testing.B) {
+	b.Run("UInt128/digits=38", func(b *testing.B) { benchmarkReduce[[4]uint64, [8]uint64](b, 38) })
+	b.Run("UInt192/digits=55", func(b *testing.B) { benchmarkReduce[[6]uint64, [12]uint64](b, 55) })
+	b.Run("UInt256/digits=50", func(b *testing.B) { benchmarkReduce[[8]uint64, [16]uint64](b, 50) })
+	b.Run("UInt384/digits=100", func(b *testing.B) { benchmarkReduce[[12]uint64, [24]uint64](b, 100) })
+}
+
+// exponents are typical of the jumps between batches of a 12 digit sieve
+var benchExponents = []uint64{195_312_500, 1_953_125_000_000, 1<<40 + 12345, 97_656_250_000_000_000}
+
+func BenchmarkPow256(b *testing.B) {
+	mask := tenTo[[8]uint64, [16]uint64](50)
+	for i := 0; i < b.N; i++ {
+		z := UInt256{[8]uint64{2}}
+		z.Pow256(NewUInt[[8]uint64, [16]uint64](benchExponents[i%len(benchExponents)]), mask)
+	}
+}
+
+func BenchmarkPowByTable(b *testing.B) {
+	mask := tenTo[[8]uint64, [16]uint64](50)
+	table := PowerTable(UInt256{[8]uint64{2}}, mask)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PowByTable(table, NewUInt[[8]uint64, [16]uint64](benchExponents[i%len(benchExponents)]), mask)
+	}
+}
+
+func FuzzPow2Mod(f *testing.F) {
+	for _, m := range boundaryValues() {
+		for _, n := range [][]byte{{}, {1}, {0xff}, {1, 0}, {1, 1}, {0xff, 0xff, 0xff, 0xff, 0xff}} {
+			f.Add(n, m)
+		}
+	}
+	f.Fuzz(func(t *testing.T, n, m []byte) {
+		for _, width := range fuzzers {
+			width.pow2Mod(t, n, m)
+		}
+	})
+}
+
+func (fuzzer[L, D]) pow2Mod(t *testing.T, n, m []byte) {
+	e, ex := fuzzValue[L, D](n[:min(len(n), 16)])
+	mask, mx := fuzzValue[L, D](m)
+	if mx.Cmp(big.NewInt(1)) <= 0 {
+		return
+	}
+	assertBig(t, new(big.Int).Exp(big.NewInt(2), ex, mx), Pow2Mod(e, mask))
+}
+
+func BenchmarkPow2Mod(b *testing.B) {
+	mask := tenTo[[8]uint64, [16]uint64](50)
+	b.Run("steps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Pow2Mod(NewUInt[[8]uint64, [16]uint64](uint64(i%200+1)), mask)
+		}
+	})
+	b.Run("jumps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Pow2Mod(NewUInt[[8]uint64, [16]uint64](benchExponents[i%len(benchExponents)]), mask)
+		}
+	})
+}
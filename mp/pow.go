@@ -0,0 +1,47 @@
+package mp
+
+// Bit returns the value of bit `i` of `a`, either 0 or 1
+func (a UInt[L, D]) Bit(i int) uint64 {
+	return (a.Content[i/32] >> (i % 32)) & 1
+}
+
+// Pow2Mod returns `2^n mod mask`. No general multiplications are needed to
+// get started since the leading bits of `n` give a power of two that fits
+// and can be built directly by shifting a single bit. The rest of the bits of
+// `n` are handled with left-to-right square-and-double where doubling is just
+// a shift and a conditional subtraction. When `n` is smaller than the width
+// of the type, as with the steps between sieve entries, the result is a
+// single shift and reduction.
+func Pow2Mod[L, D Limbs](n, mask UInt[L, D]) UInt[L, D] {
+	r := NewUInt[L, D](1)
+	width := uint(r.Bits())
+
+	i := n.BitLen() - 1
+	p := uint(0)
+	for ; i >= 0; i-- {
+		q := p<<1 | uint(n.Bit(i))
+		if q >= width {
+			break
+		}
+		p = q
+	}
+	r.Lsh(p)
+	r.Mod(mask)
+
+	for ; i >= 0; i-- {
+		r.MulMod(r, mask)
+		if n.Bit(i) != 0 {
+			r.double(mask)
+		}
+	}
+	return r
+}
+
+// double sets `a` to `2a mod mask` assuming that `a < mask`
+func (a *UInt[L, D]) double(mask UInt[L, D]) {
+	// if the sum wraps, the true value is still less than 2*mask so the
+	// wrapped subtraction gives the right answer
+	if a.Add(*a) != 0 || a.Cmp(mask) >= 0 {
+		a.Sub(mask)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"cmp"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batchSpan is a run of batches, first inclusive and end exclusive
+type batchSpan struct {
+	first, end uint64
+}
+
+// batchSpans converts a comma separated list of ranges of n into the batches
+// that cover them. Both ends of each range are rounded up to whole batches so
+// that adjacent ranges share no batches and miss none. Spans are returned in
+// order and must not overlap, and a range that rounds to no batches at all is
+// an error. The last batch reaches a little past its range, so a range that
+// ends within a batch of 2^64 is rejected.
+func batchSpans(ranges string, length uint64) ([]batchSpan, error) {
+	var spans []batchSpan
+	for _, r := range strings.Split(ranges, ",") {
+		start, limit, err := common.ParseRange(r)
+		if err != nil {
+			return nil, err
+		}
+		span := batchSpan{
+			first: roundUp(start, length),
+			end:   roundUp(limit, length),
+		}
+		// the last batch has n up to end*length plus the leadin, which is
+		// less than length
+		if span.end >= math.MaxUint64/length {
+			return nil, fmt.Errorf("range %q is too close to 2^64, it must end by %d", r, (math.MaxUint64/length-1)*length)
+		}
+		if span.end <= span.first {
+			// silently dropping it would leave its values of n unchecked
+			return nil, fmt.Errorf("range %q has no batches once both ends are rounded up to multiples of %d", r, length)
+		}
+		spans = append(spans, span)
+	}
+	slices.SortFunc(spans, func(a, b batchSpan) int {
+		return cmp.Compare(a.first, b.first)
+	})
+	for i := 1; i < len(spans); i++ {
+		if spans[i].first < spans[i-1].end {
+			return nil, fmt.Errorf("ranges in %q overlap", ranges)
+		}
+	}
+	return spans, nil
+}
+
+// roundUp returns the number of batches of `length` needed to reach n
+func roundUp(n, length uint64) uint64 {
+	batches := n / length
+	if n%length != 0 {
+		batches++
+	}
+	return batches
+}
+
+// Checkpoint is written periodically during a scan. Remaining is a value for
+// -limit that picks up where the scan left off. Solutions found so far are
+// included, but near miss records are only written at the end of a run.
+type Checkpoint struct {
+	Time      time.Time
+	Batches   uint64
+	Tests     int64
+	Solutions []uint64
+	Remaining string
+}
+
+// progress tracks which batches are complete. Batches finish out of order, so
+// only the batches before the first incomplete one count as done for the
+// purposes of a checkpoint.
+type progress struct {
+	lock      sync.Mutex
+	spans     []batchSpan
+	length    uint64
+	count     uint64
+	done      map[uint64]bool
+	completed uint64 // batches in dispatch order that are all finished
+	solutions []uint64
+
+	batches atomic.Int64
+	tests   atomic.Int64
+}
+
+func newProgress(spans []batchSpan, length uint64) *progress {
+	count := uint64(0)
+	for _, span := range spans {
+		count += span.end - span.first
+	}
+	return &progress{
+		spans:  spans,
+		length: length,
+		count:  count,
+		done:   map[uint64]bool{},
+	}
+}
+
+// batch returns the batch number of the i-th batch in dispatch order
+func (p *progress) batch(i uint64) uint64 {
+	for _, span := range p.spans {
+		if i < span.end-span.first {
+			return span.first + i
+		}
+		i -= span.end - span.first
+	}
+	panic(fmt.Sprintf("batch %d is past the end of the scan", i))
+}
+
+// finish records that a batch has been completely checked
+func (p *progress) finish(batch uint64, tests int) {
+	p.batches.Add(1)
+	p.tests.Add(int64(tests))
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.done[batch] = true
+	for p.completed < p.count && p.done[p.batch(p.completed)] {
+		delete(p.done, p.batch(p.completed))
+		p.completed++
+	}
+}
+
+// solution records a solution as soon as it is found so that checkpoints
+// include it
+func (p *progress) solution(n uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.solutions = append(p.solutions, n)
+}
+
+// checkpoint describes the state of the scan
+func (p *progress) checkpoint() Checkpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var remaining []string
+	skip := p.completed
+	for _, span := range p.spans {
+		if skip >= span.end-span.first {
+			skip -= span.end - span.first
+			continue
+		}
+		remaining = append(remaining, fmt.Sprintf("%d..%d", (span.first+skip)*p.length, span.end*p.length))
+		skip = 0
+	}
+	return Checkpoint{
+		Time:      time.Now(),
+		Batches:   p.completed,
+		Tests:     p.tests.Load(),
+		Solutions: slices.Clone(p.solutions),
+		Remaining: strings.Join(remaining, ","),
+	}
+}
+
+// checkpoints writes a checkpoint to `name` every `interval` until `stop` is
+// closed
+func (p *progress) checkpoints(name string, interval time.Duration, stop chan struct{}) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+			if err := p.writeCheckpoint(name); err != nil {
+				log.Printf("can't write checkpoint: %v", err)
+			}
+		}
+	}
+}
+
+// writeCheckpoint writes the current checkpoint to `name`
+func (p *progress) writeCheckpoint(name string) error {
+	txt, err := json.MarshalIndent(p.checkpoint(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(name, txt, 0666)
+}
+
+// serveMetrics makes counts of finished batches and tests available over HTTP
+// at /debug/vars along with the standard expvar memory statistics
+func serveMetrics(address string, p *progress) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	expvar.Publish("batches", expvar.Func(func() any { return p.batches.Load() }))
+	expvar.Publish("tests", expvar.Func(func() any { return p.tests.Load() }))
+	expvar.Publish("checkpoint", expvar.Func(func() any { return p.checkpoint() }))
+	log.Printf("metrics at http://%s/debug/vars", listener.Addr())
+	go func() {
+		log.Print(http.Serve(listener, nil))
+	}()
+	return nil
+}
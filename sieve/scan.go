@@ -6,52 +6,41 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"slices"
-	"sync"
+	"strings"
 	"time"
 )
 
-/*
-Tests the hypothesis that there are only four values of n where 2^n has all even digits
-by direct examination.
-
-This is suitable for testing several billions of values, but this is known to hold for
-values up to 2^(10^10) which is much further than can be tested with this program.
-
-This program uses the fact that there are typically less than 25 even digits for
-any value of n in the range of this program. That means we can compute 2^n mod
-mask where mask is 10^35 or so. This is good since 2^(10^9) has 300 million
-digits so the computation would become very expensive. Furthermore, it is known
-that n mod 20 must be 3, 6, 11, or 19 for n > 2. This decreases the number of
-cases we need to examine by a further factor of 5.
-*/
-var (
-	zero = mp.UInt256{}
-	two  = mp.UInt256{[8]uint64{2}}
-)
-
-type LoopAccelerator struct {
-	Mask      uint64
-	Order     int
-	Length    uint64
-	Leadin    uint64
-	EvenItems int
-	Gain      float64
-	Index     []uint64
-}
-
-type Configuration struct {
-	mu      sync.Mutex
-	Steps   []uint64
-	Bumps   []mp.UInt256
-	Mask    mp.UInt256
-	Verbose bool
+// batchSize is the number of candidates evaluated together by the table
+// kernel before their digits are checked.
+const batchSize = 256
+
+// Configuration holds the tables used by the workers. These are computed once
+// before any worker starts and are never modified afterwards so all workers
+// share a single copy.
+//
+// The gaps between sieve entries only take a modest number of distinct values
+// so Steps and Bumps hold each distinct gap and 2^gap mod Mask just once. The
+// per entry table StepIndex then only needs a small index into them.
+//
+// The integer type is chosen at run time to be just wide enough for the number
+// of digits being checked.
+type Configuration[L, D mp.Limbs] struct {
+	Steps     []uint64
+	Bumps     []mp.UInt[L, D]
+	StepIndex []uint16
+	Table     []mp.UInt[L, D]
+	Reducer   *mp.Barrett[L, D]
+	Mask      mp.UInt[L, D]
+	Digits    int
+	Known     int
+	Verbose   bool
 }
 
 type Result struct {
@@ -63,21 +52,73 @@ type Result struct {
 	Tests     int
 }
 
+// Record is a near miss, a value of n where 2^n has more trailing even digits
+// than any earlier candidate in the same worker. Residue is 2^n mod 10^digits
+// written with all of its digits, including any leading zeros, and Odd lists
+// the positions of the odd digits in it counting from the right.
 type Record struct {
-	Z      uint64
-	Digits int
+	Z       uint64
+	Digits  int
+	Residue string `json:",omitempty"`
+	Odd     []int  `json:",omitempty"`
 }
 
+/*
+Tests the hypothesis that there are only four values of n where 2^n has all even digits
+by direct examination.
+
+This is suitable for testing several billions of values, but this is known to hold for
+values up to 2^(10^10) which is much further than can be tested with this program.
+
+This program uses the fact that there are typically less than 25 even digits for
+any value of n in the range of this program. That means we can compute 2^n mod
+mask where mask is 10^35 or so. This is good since 2^(10^9) has 300 million
+digits so the computation would become very expensive. Furthermore, it is known
+that n mod 20 must be 3, 6, 11, or 19 for n > 2. This decreases the number of
+cases we need to examine by a further factor of 5.
+*/
 func main() {
 	verbose := flag.Bool("verbose", false, "verbose output")
 	digits := flag.Int("digits", 50, "Number of digits to use in search")
-	threads := flag.Int("threads", runtime.NumCPU()/2, "Number of threads to use in search")
-	sieve := flag.String("sieve", "cycle-012.json", "JSON file containing a sieve definition")
-	limitString := flag.String("limit", "10G", "Maximum value of N to search. Can use M, G, T, P and E as power of ten")
+	threads := flag.Int("threads", max(1, runtime.NumCPU()/2), "Number of threads to use in search")
+	sieve := flag.String("sieve", "cycle-012.json", "JSON file containing a sieve definition, optionally followed by comma separated higher order sieves to use as layers")
+	limitString := flag.String("limit", "10G", "Maximum value of N to search or comma separated ranges of N such as 10P..20P. Can use K, M, G, T, P and E as powers of ten, Ki, Mi and so on as powers of two, or forms like 1e15 and 2^50")
 	cpuProfile := flag.String("cpuprofile", "", "write cpu profile to file")
 	memProfile := flag.String("memprofile", "", "write memory profile to file")
+	kernel := flag.String("kernel", "chain", "Candidate evaluation kernel, either chain or table")
+	lift := flag.Bool("lift", true, "Filter candidates on the lowest digit that the sieve doesn't determine")
+	out := flag.String("out", ".", "Directory for records and checkpoints")
+	run := flag.String("run", common.NewRunID(), "Name of this run, used as a prefix for the files it writes")
+	checkpoint := flag.Duration("checkpoint", 0, "How often to write a checkpoint, zero for never")
+	metrics := flag.String("metrics", "", "Address such as localhost:8080 at which to serve progress metrics")
+	specFile := flag.String("spec", "", "YAML or JSON run spec giving values for any of the other flags")
 	flag.Parse()
 
+	if *specFile != "" {
+		spec, err := common.ReadRunSpec(*specFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = spec.Apply(flag.CommandLine)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err := common.OutputDir(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *digits < 1 {
+		log.Fatalf("Must check at least one digit, not %d", *digits)
+	}
+	if *threads < 1 {
+		log.Fatalf("Must use at least one thread, not %d", *threads)
+	}
+	if *kernel != "chain" && *kernel != "table" {
+		log.Fatalf("Unknown kernel %q, must be chain or table", *kernel)
+	}
+
 	if *cpuProfile != "" {
 		f, err := os.Create(*cpuProfile)
 		if err != nil {
@@ -105,58 +146,78 @@ func main() {
 		}
 	}()
 
-	limit := common.DecodeLimit(limitString, verbose)
-
-	mask := mp.UInt256{[8]uint64{1}}
-	for i := 0; i < *digits; i++ {
-		mask.MulSmall(10)
+	searched := uint64(0)
+	for _, r := range strings.Split(*limitString, ",") {
+		start, limit, err := common.ParseRange(r)
+		if err != nil {
+			log.Fatalf("Bad -limit: %v", err)
+		}
+		if *verbose && start > 0 {
+			log.Printf("Range: %s..%s", common.FormatLimit(start), common.FormatLimit(limit))
+		} else if *verbose {
+			log.Printf("Limit: %s", common.FormatLimit(limit))
+		}
+		searched += limit - start
 	}
 
-	config, err := readAccelerator(*sieve)
+	sieveFiles := strings.Split(*sieve, ",")
+	config, err := common.ReadAccelerator(sieveFiles[0])
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	steps := []uint64{}
-	c0 := uint64(0)
-	for _, c := range config.Index {
-		steps = append(steps, c-c0)
-		c0 = c
-	}
-	steps = append(steps, config.Length-c0)
-
-	bumps := make([]mp.UInt256, len(steps))
-	for i, step := range steps {
-		bumps[i] = two
-		bumps[i].Pow256(mp.UInt256{[8]uint64{step}}, mask)
+	err = addLayers(&config, sieveFiles[1:], *lift, *digits, *verbose)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	conf := Configuration{
-		Verbose: *verbose,
-		Steps:   steps,
-		Bumps:   bumps,
-		Mask:    mask,
+	spans, err := batchSpans(*limitString, config.Length)
+	if err != nil {
+		log.Fatalf("Bad -limit: %v", err)
 	}
-
-	solutions := []uint64{}
-	z := two
-	for n := uint64(1); n <= config.Leadin; n++ {
-		if even := checkDigits(z); even == -1 {
-			solutions = append(solutions, n)
+	progress := newProgress(spans, config.Length)
+	if *metrics != "" {
+		err = serveMetrics(*metrics, progress)
+		if err != nil {
+			log.Fatal(err)
 		}
-		z.MulMod(two, mask)
 	}
-	t0 := time.Now()
 
-	totalBatches := (limit + config.Length - 1) / config.Length
+	steps, stepIndex, err := config.StepTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *verbose {
+		log.Printf("%d sieve entries with %d distinct steps", len(config.Index), len(steps))
+	}
 
 	dispatch := make(chan uint64, *threads)
-	go dispatcher(totalBatches, dispatch, *verbose)
-
-	fmt.Printf("%d threads\n", *threads)
 	results := make(chan Result, *threads)
-	for i := 0; i < *threads; i++ {
-		go worker(i, dispatch, &conf, config, results)
+	var solutions []uint64
+	// values of n before the first batch only need checking if the scan
+	// starts at the beginning
+	leadin := len(spans) > 0 && spans[0].first == 0
+	switch width := widthFor(*digits); width {
+	case 128:
+		solutions = launch[[4]uint64, [8]uint64](*digits, *kernel, *threads, leadin, config, steps, stepIndex, dispatch, results, progress, *verbose)
+	case 192:
+		solutions = launch[[6]uint64, [12]uint64](*digits, *kernel, *threads, leadin, config, steps, stepIndex, dispatch, results, progress, *verbose)
+	case 256:
+		solutions = launch[[8]uint64, [16]uint64](*digits, *kernel, *threads, leadin, config, steps, stepIndex, dispatch, results, progress, *verbose)
+	case 384:
+		solutions = launch[[12]uint64, [24]uint64](*digits, *kernel, *threads, leadin, config, steps, stepIndex, dispatch, results, progress, *verbose)
+	default:
+		log.Fatalf("Can't check %d digits, at most %d are supported", *digits, mp.UInt384{}.MaxDigits())
+	}
+	for _, solution := range solutions {
+		progress.solution(solution)
+	}
+	t0 := time.Now()
+
+	go dispatcher(progress, dispatch, *verbose)
+	checkpointFile := filepath.Join(*out, *run+"-checkpoint.json")
+	stop := make(chan struct{})
+	if *checkpoint > 0 {
+		go progress.checkpoints(checkpointFile, *checkpoint, stop)
 	}
 
 	tests := 0
@@ -179,14 +240,14 @@ func main() {
 		}
 		tests += r.Tests
 	}
-	fr, err := os.OpenFile("records.json", os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		log.Fatal(err)
+	close(stop)
+	if *checkpoint > 0 {
+		// the final checkpoint shows that nothing remains
+		err = progress.writeCheckpoint(checkpointFile)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-	defer func(fr *os.File) {
-		_ = fr.Close()
-	}(fr)
-
 	slices.Sort(solutions)
 	slices.SortFunc(records, func(a, b Record) int {
 		return b.Digits - a.Digits
@@ -195,17 +256,164 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	_, _ = fr.Write(txt)
-	_ = fr.Close()
+	err = common.WriteFileAtomic(filepath.Join(*out, *run+"-records.json"), txt, 0666)
+	if err != nil {
+		log.Fatal(err)
+	}
 	dt := time.Since(t0).Seconds()
-	fmt.Printf("%.1f test/s, total time %.1f s\n", float64(limit)/dt, dt)
-	fmt.Printf("Limit: %d\nTests: %d\n", limit, tests)
-	fmt.Printf("Gain over brute: %f.1\n", float64(limit)/float64(tests))
+	fmt.Printf("%.1f test/s, total time %.1f s\n", float64(searched)/dt, dt)
+	fmt.Printf("Limit: %s\nTests: %d\n", *limitString, tests)
+	fmt.Printf("Gain over brute: %f.1\n", float64(searched)/float64(tests))
 	fmt.Printf("solutions = %v\n", solutions)
 }
 
-// worker is where the actual testing happens
-func worker(thread int, dispatch chan uint64, conf *Configuration, config LoopAccelerator, results chan Result) {
+// addLayers stacks the lift layer, if `lift` is set, and a layer for each of
+// the higher order sieves in `names` on top of the base sieve.
+//
+// The sieve determines the digits at positions 0 through Order, counting from
+// the units digit, and the lift layer tests the digit at position Order+1.
+// When fewer digits than that are checked, the lift layer would reject
+// candidates on a digit that checkDigits never looks at, so it is left out.
+// Likewise, a higher order sieve fixes the digits up to position Order of its
+// own, so one that reaches past the checked digits is an error.
+func addLayers(config *common.LoopAccelerator, names []string, lift bool, digits int, verbose bool) error {
+	if lift && config.Order+1 >= digits {
+		log.Printf("Not using -lift, it tests the digit at position %d but only %d digits are checked", config.Order+1, digits)
+		lift = false
+	}
+	if lift {
+		layer, err := common.LiftLayer(*config)
+		if err != nil {
+			return err
+		}
+		err = config.AddLayer(layer)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		higher, err := common.ReadAccelerator(name)
+		if err != nil {
+			return err
+		}
+		if higher.Order >= digits {
+			return fmt.Errorf("can't use %s as a layer, it fixes %d digits but only %d are checked", name, higher.Order+1, digits)
+		}
+		layer, err := common.HigherLayer(name, *config, higher)
+		if err != nil {
+			return err
+		}
+		err = config.AddLayer(layer)
+		if err != nil {
+			return err
+		}
+		if verbose {
+			log.Printf("layer %s: %d of %d candidates survive", name, layer.Survivors(), uint64(layer.Width)*layer.Period)
+		}
+	}
+	return nil
+}
+
+// widthFor returns the number of bits in the narrowest integer type that can
+// hold 10^digits. Zero is returned if no type is wide enough.
+func widthFor(digits int) int {
+	switch {
+	case digits <= mp.UInt128{}.MaxDigits():
+		return 128
+	case digits <= mp.UInt192{}.MaxDigits():
+		return 192
+	case digits <= mp.UInt256{}.MaxDigits():
+		return 256
+	case digits <= mp.UInt384{}.MaxDigits():
+		return 384
+	}
+	return 0
+}
+
+// newConfiguration builds the tables that the workers share
+func newConfiguration[L, D mp.Limbs](digits int, kernel string, config common.LoopAccelerator, steps []uint64, stepIndex []uint16, verbose bool) *Configuration[L, D] {
+	mask := mp.NewUInt[L, D](1)
+	for i := 0; i < digits; i++ {
+		if !mask.MulSmallChecked(10) {
+			log.Fatalf("10^%d doesn't fit in %d bits", digits, mask.Bits())
+		}
+	}
+
+	conf := Configuration[L, D]{
+		Verbose:   verbose,
+		Steps:     steps,
+		Bumps:     buildBumps(steps, mask),
+		StepIndex: stepIndex,
+		Mask:      mask,
+		Digits:    digits,
+		Known:     min(digits, config.Order, len(powersOfTen)-1),
+	}
+	if kernel == "table" {
+		conf.Table = buildTable(config.Index, mask)
+		conf.Reducer = mp.NewBarrett(mask)
+	}
+	return &conf
+}
+
+// checkResidues compares the scanner's own arithmetic with the sieve. Stepping
+// through the first batch with the bumps, and looking up the table if there is
+// one, must give every candidate the residue from the sieve file. Older files
+// have no residues, but either way the low digits of every candidate must be
+// even. Those digits are the same in every batch which is what lets the
+// workers skip them.
+func checkResidues[L, D mp.Limbs](conf *Configuration[L, D], config common.LoopAccelerator) error {
+	known := mp.NewUInt[L, D](powersOfTen[conf.Known])
+	z := mp.NewUInt[L, D](1)
+	for i := range config.Index {
+		z.MulMod(conf.Bumps[conf.StepIndex[i]], conf.Mask)
+		candidates := []mp.UInt[L, D]{z}
+		if conf.Table != nil {
+			candidates = append(candidates, conf.Table[i])
+		}
+		for _, c := range candidates {
+			c.Mod(known)
+			if config.Residue != nil && c.Cmp(mp.NewUInt[L, D](config.Residue[i]%powersOfTen[conf.Known])) != 0 {
+				return fmt.Errorf("2^%d ends with %s but the sieve has %d", config.Index[i], c.Digits(conf.Known), config.Residue[i])
+			}
+			if checkDigits(c, 0, conf.Known) != -1 {
+				return fmt.Errorf("2^%d ends with %s which has an odd digit", config.Index[i], c.Digits(conf.Known))
+			}
+		}
+	}
+	return nil
+}
+
+// launch builds the tables for integers of the selected width and starts the
+// workers. Values of n before the cycle starts are not covered by the sieve so
+// if leadin is set they are checked directly here and any solutions among them
+// returned.
+func launch[L, D mp.Limbs](digits int, kernel string, threads int, leadin bool, config common.LoopAccelerator, steps []uint64, stepIndex []uint16, dispatch chan uint64, results chan Result, progress *progress, verbose bool) []uint64 {
+	conf := newConfiguration[L, D](digits, kernel, config, steps, stepIndex, verbose)
+	if err := checkResidues(conf, config); err != nil {
+		log.Fatal(err)
+	}
+	mask := conf.Mask
+	two := mp.NewUInt[L, D](2)
+
+	solutions := []uint64{}
+	z := two
+	for n := uint64(1); leadin && n <= config.Leadin; n++ {
+		if even := checkDigits(z, 0, conf.Digits); even == -1 {
+			solutions = append(solutions, n)
+		}
+		z.MulMod(two, mask)
+	}
+
+	fmt.Printf("%d threads\n", threads)
+	for i := 0; i < threads; i++ {
+		go worker(i, dispatch, conf, config, results, progress)
+	}
+	return solutions
+}
+
+// worker is where the actual testing happens. Each batch is reported to
+// progress as it is finished.
+func worker[L, D mp.Limbs](thread int, dispatch chan uint64, conf *Configuration[L, D], config common.LoopAccelerator, results chan Result, progress *progress) {
 	solutions := []uint64{}
 	r := Result{
 		ID:        thread,
@@ -219,25 +427,40 @@ func worker(thread int, dispatch chan uint64, conf *Configuration, config LoopAc
 		results <- r
 	}()
 
-	conf.mu.Lock()
 	mask := conf.Mask
-
-	bumps := make([]mp.UInt256, len(conf.Bumps))
-	for i, bump := range conf.Bumps {
-		bumps[i] = bump
-	}
-
-	steps := make([]uint64, len(conf.Steps))
-	for i, step := range conf.Steps {
-		steps[i] = step
+	steps := conf.Steps
+	bumps := conf.Bumps
+	stepIndex := conf.StepIndex
+	table := conf.Table
+
+	cycleSize := len(stepIndex) - 1
+
+	// check records the outcome of testing the candidate 2^n whose low digits are z
+	check := func(n uint64, z mp.UInt[L, D]) {
+		r.Tests++
+		if even := checkDigits(z, conf.Known, conf.Digits); even == -1 {
+			r.Solutions = append(r.Solutions, n)
+			progress.solution(n)
+		} else {
+			if even > r.MaxEven {
+				r.MaxEven = even
+				r.Records = append(r.Records, Record{
+					Z:       n,
+					Digits:  even,
+					Residue: z.Digits(conf.Digits),
+					Odd:     z.Parity(conf.Digits).OddPositions(),
+				})
+			}
+		}
 	}
-	conf.mu.Unlock()
-
-	cycleSize := len(steps) - 1
 
+	survivors := make([]uint64, common.RowWords(len(config.Index)))
+	selected := make([]mp.UInt[L, D], batchSize)
+	which := make([]int, batchSize)
+	candidates := make([]mp.UInt[L, D], batchSize)
 	jobs := 0
 	n := uint64(0)
-	z := mp.UInt256{[8]uint64{1}}
+	z := mp.NewUInt[L, D](1)
 	for {
 		var (
 			job uint64
@@ -252,30 +475,50 @@ func worker(thread int, dispatch chan uint64, conf *Configuration, config LoopAc
 			}
 			break
 		}
+		tests := r.Tests
 		next := job * config.Length
-		tmp := two
-		tmp.Pow256(mp.UInt256{[8]uint64{next - n}}, mask)
-		z.MulMod(tmp, mask)
+		z.MulMod(mp.Pow2Mod(mp.NewUInt[L, D](next-n), mask), mask)
 		n = next
 
-		for i, dn := range steps[:cycleSize] {
-			n += dn
-			z.MulMod(bumps[i], mask)
-			r.Tests++
-			if even := checkDigits(z); even == -1 {
-				r.Solutions = append(r.Solutions, n)
-			} else {
-				if even > r.MaxEven {
-					r.MaxEven = even
-					r.Records = append(r.Records, Record{
-						Z:      n,
-						Digits: even,
-					})
+		// candidates that fail any of the layers have an odd digit above
+		// the sieve and need not be checked
+		live := config.Survivors(job, survivors)
+		pass := func(i int) bool {
+			return live == nil || live[i/64]&(1<<(i%64)) != 0
+		}
+
+		if table != nil {
+			// every candidate in this batch is z * 2^Index[i] so they can
+			// all be computed independently of each other
+			for i := 0; i < len(table); {
+				m := 0
+				for ; i < len(table) && m < batchSize; i++ {
+					if pass(i) {
+						selected[m] = table[i]
+						which[m] = i
+						m++
+					}
 				}
+				mp.MulModBatch(z, selected[:m], conf.Reducer, candidates)
+				for k := 0; k < m; k++ {
+					check(n+config.Index[which[k]], candidates[k])
+				}
+			}
+			progress.finish(job, r.Tests-tests)
+			continue
+		}
+
+		for i, k := range stepIndex[:cycleSize] {
+			n += steps[k]
+			z.MulMod(bumps[k], mask)
+			if pass(i) {
+				check(n, z)
 			}
 		}
-		n += steps[cycleSize]
-		z.MulMod(bumps[cycleSize], mask)
+		last := stepIndex[cycleSize]
+		n += steps[last]
+		z.MulMod(bumps[last], mask)
+		progress.finish(job, r.Tests-tests)
 	}
 	r.Success = true
 	if conf.Verbose {
@@ -285,8 +528,9 @@ func worker(thread int, dispatch chan uint64, conf *Configuration, config LoopAc
 
 // dispatcher sends small batches of work to the workers via a channel
 // each work is iteration through the repetition cycle we got from the
-// cycle detector program.
-func dispatcher(totalBatches uint64, dispatch chan uint64, verbose bool) {
+// cycle detector program. The batches to send are given by progress.
+func dispatcher(progress *progress, dispatch chan uint64, verbose bool) {
+	totalBatches := progress.count
 	step := (totalBatches + 19) / 20
 	t0 := time.Now()
 	tick := time.NewTicker(time.Second)
@@ -322,7 +566,7 @@ func dispatcher(totalBatches uint64, dispatch chan uint64, verbose bool) {
 				report()
 				lastReport = time.Now()
 			}
-		case dispatch <- i:
+		case dispatch <- progress.batch(i):
 			i++
 			if verbose && i%step == 0 {
 				if normalReporting || time.Since(lastReport).Seconds() > 5 {
@@ -338,36 +582,42 @@ func dispatcher(totalBatches uint64, dispatch chan uint64, verbose bool) {
 	close(dispatch)
 }
 
-func readAccelerator(name string) (LoopAccelerator, error) {
-	config := LoopAccelerator{}
-
-	f, err := os.Open(name)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func(f *os.File) {
-		_ = f.Close()
-	}(f)
-	txt, err := io.ReadAll(f)
-	if err != nil {
-		log.Fatal(err)
+// buildBumps computes 2^step mod mask for each distinct step. Steps are almost
+// always narrower than the integers so each bump is just a shift and reduction.
+func buildBumps[L, D mp.Limbs](steps []uint64, mask mp.UInt[L, D]) []mp.UInt[L, D] {
+	bumps := make([]mp.UInt[L, D], len(steps))
+	for i, step := range steps {
+		bumps[i] = mp.Pow2Mod(mp.NewUInt[L, D](step), mask)
 	}
-	_ = f.Close()
-	err = json.Unmarshal(txt, &config)
-	if err != nil {
-		log.Fatal(err)
+	return bumps
+}
+
+// buildTable computes 2^index mod mask for every entry in the sieve. These
+// are the multipliers that take the start of a batch directly to each candidate.
+// They use Pow2Mod like the bumps and jumps so there is only one way that the
+// scanner computes powers of two.
+func buildTable[L, D mp.Limbs](index []uint64, mask mp.UInt[L, D]) []mp.UInt[L, D] {
+	table := make([]mp.UInt[L, D], len(index))
+	for i, k := range index {
+		table[i] = mp.Pow2Mod(mp.NewUInt[L, D](k), mask)
 	}
-	return config, err
+	return table
 }
 
-// checkDigits returns -1 if all of the digits in z are even. If not, the
-// position counting from the right is returned.
-func checkDigits(z mp.UInt256) int {
-	for j := 0; z.Cmp(zero) > 0; j++ {
-		digit := z.DivModSmall(10)
-		if digit%2 == 1 {
-			return j
-		}
+// powersOfTen holds every power of ten that fits in a uint64
+var powersOfTen = func() (r [20]uint64) {
+	r[0] = 1
+	for i := 1; i < len(r); i++ {
+		r[i] = 10 * r[i-1]
 	}
-	return -1
+	return r
+}()
+
+// checkDigits returns -1 if the low `digits` digits of z are all even. If not,
+// the position of the first odd digit counting from the right is returned. The
+// lowest `known` digits are already known to be even and are skipped without
+// being examined. This is mp's parity query, the same one that the records
+// use, in the form that stops at the first odd digit.
+func checkDigits[L, D mp.Limbs](z mp.UInt[L, D], known, digits int) int {
+	return z.FirstOddAbove(known, digits)
 }
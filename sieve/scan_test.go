@@ -0,0 +1,290 @@
+package main
+
+import (
+	"EvenDigits/common"
+	"EvenDigits/mp"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func BenchmarkCheckDigits(b *testing.B) {
+	mask := mp.NewUInt[[8]uint64, [16]uint64](1)
+	for i := 0; i < 50; i++ {
+		mask.MulSmall(10)
+	}
+	// a typical candidate has an odd digit in the first few places, but a
+	// few have many even digits first
+	table := mp.PowerTable(mp.NewUInt[[8]uint64, [16]uint64](2), mask)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		checkDigits(table[i%len(table)], 0, 50)
+	}
+}
+
+// loadSieve reads one of the sieves from the top of the repository along with
+// the lift layer, the same way the scanner does by default
+func loadSieve(b testing.TB, name string) (common.LoopAccelerator, []uint64, []uint16) {
+	name = "../" + name
+	if _, err := os.Stat(name); err != nil {
+		b.Skipf("no sieve %s", name)
+	}
+	config, err := common.ReadAccelerator(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	layer, err := common.LiftLayer(config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := config.AddLayer(layer); err != nil {
+		b.Fatal(err)
+	}
+	steps, stepIndex, err := config.StepTable()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return config, steps, stepIndex
+}
+
+// benchmarkBatch runs a single worker over b.N batches of the sieve
+func benchmarkBatch[L, D mp.Limbs](b *testing.B, sieve string, kernel string, digits int) {
+	config, steps, stepIndex := loadSieve(b, sieve)
+	conf := newConfiguration[L, D](digits, kernel, config, steps, stepIndex, false)
+
+	dispatch := make(chan uint64, b.N)
+	for i := 0; i < b.N; i++ {
+		dispatch <- uint64(i + 1)
+	}
+	close(dispatch)
+	results := make(chan Result, 1)
+
+	b.ResetTimer()
+	worker(0, dispatch, conf, config, results, newProgress([]batchSpan{{1, uint64(b.N) + 1}}, config.Length))
+	b.StopTimer()
+
+	r := <-results
+	b.ReportMetric(float64(r.Tests)/float64(b.N), "tests/batch")
+	b.ReportMetric(float64(config.Length)*float64(b.N)/b.Elapsed().Seconds(), "candidates/s")
+}
+
+// benchmarkDigits runs benchmarkBatch with the integer width that the scanner
+// picks for `digits`
+func benchmarkDigits(b *testing.B, sieve string, kernel string, digits int) {
+	switch widthFor(digits) {
+	case 128:
+		benchmarkBatch[[4]uint64, [8]uint64](b, sieve, kernel, digits)
+	case 192:
+		benchmarkBatch[[6]uint64, [12]uint64](b, sieve, kernel, digits)
+	case 256:
+		benchmarkBatch[[8]uint64, [16]uint64](b, sieve, kernel, digits)
+	case 384:
+		benchmarkBatch[[12]uint64, [24]uint64](b, sieve, kernel, digits)
+	default:
+		b.Fatalf("no width for %d digits", digits)
+	}
+}
+
+// scanBatches runs the scanner over the leadin and the first few batches of a
+// sieve with a single worker and returns the solutions and the worker's result
+func scanBatches[L, D mp.Limbs](t *testing.T, sieve string, kernel string, digits int, batches uint64) ([]uint64, Result) {
+	config, steps, stepIndex := loadSieve(t, sieve)
+	dispatch := make(chan uint64, batches)
+	for j := uint64(0); j < batches; j++ {
+		dispatch <- j
+	}
+	close(dispatch)
+	results := make(chan Result, 1)
+	progress := newProgress([]batchSpan{{0, batches}}, config.Length)
+	solutions := launch[L, D](digits, kernel, 1, true, config, steps, stepIndex, dispatch, results, progress, false)
+	r := <-results
+	assert.True(t, r.Success)
+	return append(solutions, r.Solutions...), r
+}
+
+func TestKernelsAgree(t *testing.T) {
+	for _, digits := range []int{38, 70} {
+		var solutions [2][]uint64
+		var results [2]Result
+		for k, kernel := range []string{"chain", "table"} {
+			switch widthFor(digits) {
+			case 128:
+				solutions[k], results[k] = scanBatches[[4]uint64, [8]uint64](t, "cycle-006.json", kernel, digits, 4)
+			case 256:
+				solutions[k], results[k] = scanBatches[[8]uint64, [16]uint64](t, "cycle-006.json", kernel, digits, 4)
+			default:
+				t.Fatalf("no width for %d digits", digits)
+			}
+			assert.Equal(t, []uint64{1, 2, 3, 6, 11}, solutions[k], "%s kernel, %d digits", kernel, digits)
+		}
+		assert.Equal(t, solutions[0], solutions[1], "%d digits", digits)
+		assert.Equal(t, results[0].Tests, results[1].Tests, "%d digits", digits)
+		assert.Equal(t, results[0].MaxEven, results[1].MaxEven, "%d digits", digits)
+		assert.Equal(t, results[0].Records, results[1].Records, "%d digits", digits)
+		assert.NotEmpty(t, results[0].Records)
+	}
+}
+
+func BenchmarkWorkerBatch(b *testing.B) {
+	for _, sieve := range []string{"cycle-009.json", "cycle-012.json"} {
+		for _, kernel := range []string{"chain", "table"} {
+			// one case for each width, 50 digits being the default
+			for _, digits := range []int{38, 50, 70, 100} {
+				name := fmt.Sprintf("%s/%s/digits=%d/UInt%d", sieve, kernel, digits, widthFor(digits))
+				b.Run(name, func(b *testing.B) {
+					benchmarkDigits(b, sieve, kernel, digits)
+				})
+			}
+		}
+	}
+}
+
+func TestCheckDigits(t *testing.T) {
+	z := mp.NewUInt[[4]uint64, [8]uint64](0)
+	z.SetString("28000000006448")
+	assert.Equal(t, -1, checkDigits(z, 0, 15), "all even digits")
+	z.SetString("123000000006448")
+	for _, known := range []int{0, 4, 9, 12} {
+		assert.Equal(t, 12, checkDigits(z, known, 15), "skipping %d", known)
+	}
+	// digits beyond the ones checked don't count
+	assert.Equal(t, -1, checkDigits(z, 0, 12))
+}
+
+func TestCheckResidues(t *testing.T) {
+	config, steps, stepIndex := loadSieve(t, "cycle-006.json")
+	assert.Len(t, config.Residue, len(config.Index), "the sieve file should have residues")
+	for _, kernel := range []string{"chain", "table"} {
+		conf := newConfiguration[[4]uint64, [8]uint64](30, kernel, config, steps, stepIndex, false)
+		assert.NoError(t, checkResidues(conf, config), kernel)
+		// fewer digits than the sieve only checks what it can
+		conf = newConfiguration[[4]uint64, [8]uint64](4, kernel, config, steps, stepIndex, false)
+		assert.NoError(t, checkResidues(conf, config), kernel)
+	}
+
+	// the digits are still checked when there are no residues
+	residues := config.Residue
+	config.Residue = nil
+	conf := newConfiguration[[4]uint64, [8]uint64](30, "chain", config, steps, stepIndex, false)
+	assert.NoError(t, checkResidues(conf, config))
+
+	config.Residue = slices.Clone(residues)
+	config.Residue[17] += 2
+	assert.ErrorContains(t, checkResidues(conf, config), "but the sieve has")
+}
+
+func TestAddLayers(t *testing.T) {
+	if _, err := os.Stat("../cycle-006.json"); err != nil {
+		t.Skip("no sieve file")
+	}
+	for digits, layers := range map[int]int{6: 0, 7: 0, 8: 1, 50: 1} {
+		config, err := common.ReadAccelerator("../cycle-006.json")
+		assert.NoError(t, err)
+		// the lift layer tests the digit at position 7 so it needs at least 8 digits
+		assert.NoError(t, addLayers(&config, nil, true, digits, false))
+		assert.Len(t, config.Layers, layers, "%d digits", digits)
+	}
+
+	// a higher order sieve can only be a layer if every digit it fixes is checked
+	if _, err := os.Stat("../cycle-009.json"); err != nil {
+		t.Skip("no higher order sieve file")
+	}
+	for digits, ok := range map[int]bool{9: false, 10: true, 50: true} {
+		config, err := common.ReadAccelerator("../cycle-006.json")
+		assert.NoError(t, err)
+		err = addLayers(&config, []string{"../cycle-009.json"}, false, digits, false)
+		if ok {
+			assert.NoError(t, err)
+			assert.Len(t, config.Layers, 1)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestBatchSpans(t *testing.T) {
+	spans, err := batchSpans("0..100,1000..2000,100..999", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []batchSpan{{0, 1}, {1, 10}, {10, 20}}, spans)
+
+	_, err = batchSpans("0..250,120..300", 100)
+	assert.Error(t, err)
+
+	// ranges that lie within a batch would otherwise be dropped unchecked
+	for _, limit := range []string{"1..2", "0", "101..199", "0..100,150..160"} {
+		_, err = batchSpans(limit, 100)
+		assert.ErrorContains(t, err, "no batches", limit)
+	}
+
+	// limits near 2^64 must not wrap around when rounded up to whole batches
+	length := uint64(4 * 5 * 5 * 5 * 5 * 5)
+	last := (math.MaxUint64/length - 1) * length
+	spans, err = batchSpans(fmt.Sprintf("%d..%d", last-length, last), length)
+	assert.NoError(t, err)
+	assert.Equal(t, []batchSpan{{last/length - 1, last / length}}, spans)
+	_, err = batchSpans("18E", length)
+	assert.NoError(t, err)
+	for _, limit := range []string{fmt.Sprint(last + 1), "18446744073709551615", "18446744073709551615..18446744073709551615"} {
+		_, err = batchSpans(limit, length)
+		assert.Error(t, err, limit)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	spans, err := batchSpans("0..300,1000..1500", 100)
+	assert.NoError(t, err)
+	p := newProgress(spans, 100)
+
+	// remaining returns the unfinished batches that a resumed run would scan
+	remaining := func() []batchSpan {
+		left, err := batchSpans(p.checkpoint().Remaining, 100)
+		assert.NoError(t, err)
+		return left
+	}
+	assert.Equal(t, spans, remaining())
+
+	// batches finished out of order only count once everything before them
+	// is done, even across spans
+	p.finish(1, 10)
+	p.finish(10, 10)
+	assert.Equal(t, uint64(0), p.checkpoint().Batches)
+	p.finish(0, 10)
+	assert.Equal(t, uint64(2), p.checkpoint().Batches)
+	assert.Equal(t, []batchSpan{{2, 3}, {10, 15}}, remaining())
+	p.finish(2, 10)
+	assert.Equal(t, uint64(4), p.checkpoint().Batches)
+	assert.Equal(t, []batchSpan{{11, 15}}, remaining())
+	p.finish(13, 10)
+	assert.Equal(t, uint64(4), p.checkpoint().Batches)
+	assert.Equal(t, "1100..1500", p.checkpoint().Remaining)
+
+	p.solution(11)
+	p.solution(1105)
+	c := p.checkpoint()
+	assert.Equal(t, []uint64{11, 1105}, c.Solutions)
+	assert.Equal(t, int64(50), c.Tests)
+
+	for _, batch := range []uint64{12, 14, 11} {
+		p.finish(batch, 10)
+	}
+	c = p.checkpoint()
+	assert.Equal(t, uint64(8), c.Batches)
+	assert.Equal(t, "", c.Remaining)
+
+	// the file has the same contents
+	name := filepath.Join(t.TempDir(), "run-checkpoint.json")
+	assert.NoError(t, p.writeCheckpoint(name))
+	txt, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	var written Checkpoint
+	assert.NoError(t, json.Unmarshal(txt, &written))
+	assert.Equal(t, c.Batches, written.Batches)
+	assert.Equal(t, c.Tests, written.Tests)
+	assert.Equal(t, c.Solutions, written.Solutions)
+	assert.Equal(t, c.Remaining, written.Remaining)
+}
@@ -28,11 +28,17 @@ func main() {
 	limitString := flag.String(
 		"limit",
 		"10M",
-		"Maximum value of N to search. Can use M, G, T, P and E as power of ten",
+		"Maximum value of N to search. Can use K, M, G, T, P and E as powers of ten, Ki, Mi and so on as powers of two, or forms like 1e15 and 2^50",
 	)
 	digits := flag.Int64("digits", 50, "Number of digits to retain in search")
 	flag.Parse()
-	limit := common.DecodeLimit(limitString, verbose)
+	limit, err := common.ParseLimit(*limitString)
+	if err != nil {
+		log.Fatalf("Bad -limit: %v", err)
+	}
+	if *verbose {
+		log.Printf("Limit: %s", common.FormatLimit(limit))
+	}
 
 	zero := decimal.NewFromInt(0)
 	two := decimal.NewFromInt(2)